@@ -23,11 +23,14 @@ import (
 	"reflect"
 	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/JessonChan/canlog"
 	"github.com/JessonChan/jsun"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/sessions"
 )
 
 type any interface{}
@@ -39,12 +42,42 @@ type Can struct {
 	staticRootPath string
 	tplSuffix      []string
 	debugTpl       bool
+	// 构建时是否检查推断出的模板，见 Opts.CheckTpl
+	tplCheck bool
 
 	routeMux         *routeDispatcher
 	filterDispatcher map[FilterType]*filterDispatcher
-	tplFuncMap       map[string]interface{}
-	tplNameMap       map[string]bool
-	fallbackHandler  http.Handler
+	// removedURIs/removedFilters 被移除的结构体和filter，RegisterURI/RegisterFilter注册的不再自动加入
+	removedURIs    map[reflect.Type]bool
+	removedFilters map[FilterType]bool
+	// mu 保护注册信息和路由表的构建
+	mu sync.Mutex
+	// table 当前生效的*routeTable，运行时增删路由通过原子替换更新
+	table           atomic.Value
+	tplFuncMap      map[string]interface{}
+	tplNameMap      map[string]bool
+	fallbackHandler http.Handler
+	// 是否开启/_cango/routes路由表页面
+	debugRoutes bool
+	// 不规范路径的处理方式
+	pathPolicy PathPolicy
+	// 约定路由的命名方式，NamingNone 表示不开启
+	routeNaming RouteNaming
+	// ModelView没有指定模板时的查找方式
+	tplResolver TplResolver
+	// 处理函数返回的error到状态码的映射
+	errorMapper func(err error) int
+	// 通过Service注册的控制器依赖
+	services []service
+	// 全局和路由上的参数Provider
+	providers      providerMap
+	routeProviders map[string]providerMap
+	// 运行中最后一次修改路由的错误
+	err error
+	// 已经遍历过的静态文件目录
+	staticWalked string
+	// groups 所有的分组，见 snapshot
+	groups []*Group
 }
 
 var defaultAddr = Addr{Host: "", Port: 8080}
@@ -52,14 +85,19 @@ var defaultAddr = Addr{Host: "", Port: 8080}
 // NewCan 生成服务对象，name 为生成的对象名，可以为空
 // 一般，只有我们在工程中需要注册多个web服务时才需要设置
 func NewCan(name ...string) *Can {
-	return &Can{
+	can := &Can{
 		name:             append(name, "")[0],
 		srv:              &http.Server{Addr: defaultAddr.String()},
-		routeMux:         &routeDispatcher{dispatcher: newCanMux(), ctrlEntryMap: map[string]ctrlEntry{}},
+		routeMux:         &routeDispatcher{ctrlEntryMap: map[string]ctrlEntry{}},
 		filterDispatcher: map[FilterType]*filterDispatcher{},
+		removedURIs:      map[reflect.Type]bool{},
+		removedFilters:   map[FilterType]bool{},
 		tplFuncMap:       map[string]interface{}{},
 		tplNameMap:       map[string]bool{},
 	}
+	// 模板中可以直接使用urlfor生成路由地址
+	can.tplFuncMap["urlfor"] = can.URLFor
+	return can
 }
 
 type Addr struct {
@@ -89,6 +127,13 @@ type Opts struct {
 	CookieSessionSecure string
 	// TLS 文件
 	CertFile, KeyFile string
+	// 启动时是否输出路由表
+	PrintRoutes bool
+	// 不规范路径（如/a/b/）的处理方式，默认为PathLenient
+	PathPolicy PathPolicy
+	// 启动时是否检查返回ModelView的处理函数推断出的模板，模板不存在时Run和Validate返回错误
+	// 自己设置ModelView.Tpl的处理函数在URI字段上加 tpl:"-" 标签跳过检查；返回interface{}的处理函数不做检查
+	CheckTpl bool
 }
 
 var defaultTplSuffix = []string{".tpl", ".html"}
@@ -153,10 +198,26 @@ func (can *Can) Run(as ...interface{}) error {
 	can.staticRootPath = filepath.Clean(can.rootPath + "/" + opts.StaticDir)
 	can.tplSuffix = opts.TplSuffix
 	can.debugTpl = opts.DebugTpl
-	can.buildStaticRoute()
-	can.buildRoute()
-	// 务必要先构建route再去构建filter
-	can.buildFilter()
+	can.tplCheck = opts.CheckTpl
+	if opts.PathPolicy != PathLenient {
+		can.pathPolicy = opts.PathPolicy
+	}
+	table := can.build()
+	if err := checkRoutes(table); err != nil {
+		canlog.CanError(err)
+		return err
+	}
+	if err := checkInject(table); err != nil {
+		canlog.CanError(err)
+		return err
+	}
+	if err := can.checkTpl(table); err != nil {
+		canlog.CanError(err)
+		return err
+	}
+	if opts.PrintRoutes {
+		table.printRoutes(canlog.GetLogger().Writer())
+	}
 
 	// 初化session
 	if opts.CookieSessionKey != "" && gorillaStore == nil {
@@ -183,9 +244,7 @@ type GinHandler struct {
 }
 
 func (can *Can) ToGins() []*GinHandler {
-	can.buildStaticRoute()
-	can.buildRoute()
-	return can.routeMux.dispatcher.(*canDispatcher).Gins()
+	return can.build().dispatcher.(*canDispatcher).Gins(can.renderError)
 }
 func (p *Can) Shutdown() *Can {
 	p.srv.Shutdown(context.Background())
@@ -232,6 +291,9 @@ func getOpts(as []interface{}) *Opts {
 				optsPtr.TplSuffix = opts.TplSuffix
 			}
 			optsPtr.DebugTpl = opts.DebugTpl
+			optsPtr.PrintRoutes = opts.PrintRoutes
+			optsPtr.PathPolicy = opts.PathPolicy
+			optsPtr.CheckTpl = opts.CheckTpl
 		}
 	}
 	return optsPtr
@@ -251,6 +313,14 @@ func getRootPath() string {
 }
 
 func (can *Can) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodHead {
+		rw = &headResponseWriter{ResponseWriter: rw}
+	}
+	// 整个请求使用同一个路由表，运行时的修改不会影响正在处理的请求
+	table := can.loadTable()
+	if can.checkPath(table, rw, r) {
+		return
+	}
 	request := &WebRequest{
 		ResponseWriter: rw,
 		Request:        r,
@@ -262,7 +332,7 @@ func (can *Can) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 			runtime.Stack(buf[:], false)
 			canlog.CanError(err)
 			canlog.CanError(string(buf[0:]))
-			request.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+			errorHandleMap[http.StatusInternalServerError](request.ResponseWriter, r)
 		}
 	}()
 
@@ -271,9 +341,8 @@ func (can *Can) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	var filterReturn interface{}
 	var needHandle = true
 	var filterChan []Filter
-	for _, dsp := range can.filterDispatcher {
-		match := doubleMatch(dsp.dispatcher, r)
-		if match.Error() == nil {
+	for _, dsp := range table.filters {
+		if filterMatch(dsp.dispatcher, r) {
 			filterChan = append(filterChan, dsp.filter)
 			ri := dsp.filter.PreHandle(request)
 			if rt, ok := ri.(bool); ok {
@@ -293,14 +362,14 @@ func (can *Can) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		}
 	}
 	if needStop {
-		request.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+		errorHandleMap[http.StatusInternalServerError](request.ResponseWriter, r)
 		return
 	}
 	var handleReturn interface{}
 	var statusCode int
 
 	if needHandle {
-		handleReturn, statusCode = serve(can.routeMux, request)
+		handleReturn, statusCode = serve(table, request)
 		if statusCode == serveFallbackCode {
 			if can.fallbackHandler != nil {
 				can.fallbackHandler.ServeHTTP(request.ResponseWriter, r)
@@ -325,6 +394,9 @@ func (can *Can) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 	switch handleReturn.(type) {
 	case ModelView:
 		mv := handleReturn.(ModelView)
+		if mv.Tpl == "" {
+			mv.Tpl, _ = can.impliedTpl(request.invoker)
+		}
 		tpl := can.lookupTpl(mv.Tpl)
 		if tpl == nil {
 			canlog.CanError("template not find", mv.Tpl, mv.Model)
@@ -358,7 +430,7 @@ func (can *Can) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		}
 		request.ResponseWriter.Header().Set("Content-Type", "text/html; charset=utf-8")
 		request.ResponseWriter.WriteHeader(code)
-		_, err := request.ResponseWriter.Write([]byte(handleReturn.(Content).String))
+		_, err := request.ResponseWriter.Write([]byte(handleReturn.(ContentWithCode).String))
 		if err != nil {
 			canlog.CanError(err)
 		}
@@ -393,19 +465,29 @@ func (can *Can) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 			defer f.Close()
 			http.ServeContent(rw, r, path, time.Now(), f)
 		}
+	case Problem:
+		writeProblem(request.ResponseWriter, r, handleReturn.(Problem))
+	case EventStream:
+		serveEvents(request.ResponseWriter, r, handleReturn.(EventStream))
+	case WebSocket:
+		serveWebSocket(request.ResponseWriter, r, handleReturn.(WebSocket))
+	case errorReturn:
+		can.renderError(request.ResponseWriter, r, handleReturn.(errorReturn).err)
 	case DoNothing:
-		if statusCode == http.StatusNotFound {
-			errorHandleMap[404](request.ResponseWriter, r)
+		if fn, ok := errorHandleMap[statusCode]; ok {
+			fn(request.ResponseWriter, r)
+		} else if statusCode != http.StatusOK {
+			request.ResponseWriter.WriteHeader(statusCode)
 		}
 	default:
-		request.ResponseWriter.WriteHeader(statusCode)
 		bs, err := responseJsonHandler(handleReturn)
-		if err == nil {
-			_, _ = request.ResponseWriter.Write(bs)
-		} else {
+		if err != nil {
 			canlog.CanError(err)
-			_, _ = request.ResponseWriter.Write([]byte("{}"))
+			errorHandleMap[http.StatusInternalServerError](request.ResponseWriter, r)
+			break
 		}
+		request.ResponseWriter.WriteHeader(statusCode)
+		_, _ = request.ResponseWriter.Write(bs)
 	}
 	// postHandle
 	for _, f := range filterChan {
@@ -422,113 +504,185 @@ func doubleMatch(mux dispatcher, req *http.Request) matcher {
 		if originalPath == req.URL.Path {
 			return match
 		}
-		match = mux.Match(req)
+		cleanMatch := mux.Match(req)
 		req.URL.Path = originalPath
+		// 第二次也没有找到路径时，保留第一次的结果（可能是方法不匹配）
+		if _, ok := cleanMatch.Error().(*methodNotAllowedError); cleanMatch.Error() == nil || ok {
+			return cleanMatch
+		}
 		return match
 	}
 	return match
 }
 
+// matchRequest 匹配请求，HEAD请求没有对应的路由时使用GET的路由
+func matchRequest(mux dispatcher, req *http.Request) matcher {
+	match := doubleMatch(mux, req)
+	if req.Method != http.MethodHead || match.Error() == nil {
+		return match
+	}
+	if mna, ok := match.Error().(*methodNotAllowedError); ok && mna.allows(http.MethodGet) {
+		req.Method = http.MethodGet
+		getMatch := doubleMatch(mux, req)
+		req.Method = http.MethodHead
+		return getMatch
+	}
+	return match
+}
+
+// filterMatch 判断filter是否作用于请求，自动返回的OPTIONS也使用路径上的filter，如设置CORS的header
+func filterMatch(mux dispatcher, req *http.Request) bool {
+	err := matchRequest(mux, req).Error()
+	if _, ok := err.(*methodNotAllowedError); ok && req.Method == http.MethodOptions {
+		return true
+	}
+	return err == nil
+}
+
+// headResponseWriter HEAD请求只需要返回header，丢弃所有写入的body
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (hw *headResponseWriter) Write(bs []byte) (int, error) {
+	return len(bs), nil
+}
+
 const serveFallbackCode = -1
 
 const mimeJSON = "application/json"
 
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
 func serve(mux dispatcher, request *WebRequest) (interface{}, int) {
 	req := request.Request
-	match := doubleMatch(mux, req)
+	match := matchRequest(mux, req)
 	if match.Error() != nil {
+		if mna, ok := match.Error().(*methodNotAllowedError); ok {
+			request.ResponseWriter.Header().Set("Allow", strings.Join(mna.implicit(), ", "))
+			// 没有显式注册OPTIONS方法时，自动返回支持的方法
+			if req.Method == http.MethodOptions {
+				return nil, http.StatusNoContent
+			}
+			canlog.CanError(req.Method, req.URL.Path, match.Error())
+			return nil, http.StatusMethodNotAllowed
+		}
 		canlog.CanError(req.Method, req.URL.Path, match.Error())
 		return nil, serveFallbackCode
 	}
 	invoker := match.Forwarder().GetInvoker()
+	request.invoker = invoker
+	// 挂载的handler自己处理请求，不需要解析参数
+	if invoker.kind == invokeByHandler {
+		invoker.handler.ServeHTTP(request.ResponseWriter, req)
+		return nil, http.StatusOK
+	}
+	if invoker.timeout > 0 {
+		return serveTimeout(invoker, match, request)
+	}
+	return invoke(invoker, match, request)
+}
+
+// invoke 绑定参数并执行处理函数
+func invoke(invoker *Invoker, match matcher, request *WebRequest) (interface{}, int) {
+	req := request.Request
 	uriRequestValue := reflect.ValueOf(newContext(request))
-	callerIn := make([]reflect.Value, invoker.Type.NumIn())
-	cookies := req.Cookies()
+	plan := invoker.plan
+	if plan == nil {
+		plan = bindPlanOf(invoker.Type)
+	}
+	callerIn := make([]reflect.Value, len(plan.params))
+	var cookies []*http.Cookie
+	if plan.entities[cookieEntity] {
+		cookies = req.Cookies()
+	}
 	_ = req.ParseForm()
-	gs, _ := gorillaStore.Get(request.Request, cangoSessionKey)
+	var gs *sessions.Session
+	if plan.entities[sessionEntity] {
+		gs, _ = gorillaStore.Get(request.Request, cangoSessionKey)
+	}
+	vars := match.GetVars()
 	var bodyBytes []byte
 	var isParse bool
 
-	for i := 0; i < len(callerIn); i++ {
-		in := invoker.Type.In(i)
-		callerIn[i] = newValue(in)
-		if in.Implements(uriType) {
-			if in == uriType {
-				callerIn[i].Set(uriRequestValue)
-			} else {
-				uriFiled := value(callerIn[i]).FieldByName(uriName)
-				if uriFiled.IsValid() && uriFiled.CanSet() {
-					uriFiled.Set(uriRequestValue)
-				}
-			}
-		}
-		// 先解析form
-		// 再赋值path value，如果form中包含和path中相同的变量，被path覆盖
-		// 读取header，只赋值有header标签的变量
-		// 读取cookie，只赋值有cookie标签的变量
-		// 解析session，赋值有session标签的变量
-		reqHolder := func(valueKey string, et entityType) *entityValue {
-			switch et {
-			case cookieEntity:
-				for _, cookie := range cookies {
-					if cookie.Name == valueKey {
-						return &entityValue{
-							enc:   stringFlag,
-							key:   cookie.Name,
-							value: cookie.Value,
-						}
-					}
-				}
-				return nil
-			case headerEntity:
-				if i, ok := req.Header[valueKey]; ok {
+	// 先解析form
+	// 再赋值path value，如果form中包含和path中相同的变量，被path覆盖
+	// 读取header，只赋值有header标签的变量
+	// 读取cookie，只赋值有cookie标签的变量
+	// 解析session，赋值有session标签的变量
+	reqHolder := func(valueKey string, et entityType) *entityValue {
+		switch et {
+		case cookieEntity:
+			for _, cookie := range cookies {
+				if cookie.Name == valueKey {
 					return &entityValue{
 						enc:   stringFlag,
-						key:   valueKey,
-						value: i,
+						key:   cookie.Name,
+						value: cookie.Value,
 					}
 				}
-				return nil
-			case sessionEntity:
-				if i, ok := gs.Values[valueKey]; ok {
-					return &entityValue{
-						enc:   gobBytes,
-						key:   valueKey,
-						value: i,
-					}
+			}
+			return nil
+		case headerEntity:
+			if i, ok := req.Header[valueKey]; ok {
+				return &entityValue{
+					enc:   stringFlag,
+					key:   valueKey,
+					value: i,
 				}
+			}
+			return nil
+		case sessionEntity:
+			if gs == nil {
 				return nil
-			default:
-				if v, ok := match.GetVars()[valueKey]; ok {
-					return &entityValue{
-						enc:   stringFlag,
-						key:   valueKey,
-						value: v,
-					}
+			}
+			if i, ok := gs.Values[valueKey]; ok {
+				return &entityValue{
+					enc:   gobBytes,
+					key:   valueKey,
+					value: i,
 				}
-				if v, ok := req.Form[valueKey]; ok {
-					return &entityValue{
-						enc:   strSliceFlag,
-						key:   valueKey,
-						value: v,
-					}
+			}
+			return nil
+		default:
+			if v, ok := vars[valueKey]; ok {
+				return &entityValue{
+					enc:   stringFlag,
+					key:   valueKey,
+					value: v,
+				}
+			}
+			if v, ok := req.Form[valueKey]; ok {
+				return &entityValue{
+					enc:   strSliceFlag,
+					key:   valueKey,
+					value: v,
 				}
-				return nil
 			}
+			return nil
 		}
+	}
 
-		doDecode(addr(callerIn[i]), reqHolder, fieldTagNames)
-		// TODO redesign 这个接口
-		if in.Implements(constructorType) {
-			uriFiled := value(callerIn[i]).FieldByName(constructorTypeName)
-			if uriFiled.IsValid() && uriFiled.CanSet() {
-				uriFiled.Set(valueOfEmptyConstructor)
+	isJSON := strings.ToLower(req.Header.Get("Content-Type")) == mimeJSON
+	for i, pp := range plan.params {
+		if p, ok := invoker.providers[i]; ok {
+			arg, err := provide(p, request)
+			if err != nil {
+				return errorReturn{err: err}, http.StatusOK
 			}
-			addr(callerIn[i]).Interface().(Constructor).Construct(request)
+			callerIn[i] = arg
+			continue
+		}
+		// 接收者先在新的值上绑定请求，再和控制器原型合并，防止请求修改预设的字段和注入的依赖
+		receiver := i == 0 && invoker.kind == invokeByReceiver
+		if receiver {
+			callerIn[i] = pp.bindArg(uriRequestValue, reqHolder)
+		} else {
+			callerIn[i] = pp.newArg(uriRequestValue, request, reqHolder)
 		}
 		// 如果是json data的类型
 		// 这种情况主要是在请求使用json对象直接提交的时候
-		if strings.ToLower(request.Request.Header.Get("Content-Type")) == mimeJSON {
+		if isJSON {
 			if !isParse {
 				isParse = true
 				bs, err := io.ReadAll(request.Request.Body)
@@ -539,10 +693,14 @@ func serve(mux dispatcher, request *WebRequest) (interface{}, int) {
 			}
 			to := addr(callerIn[i]).Interface()
 			err := jsun.Unmarshal(bodyBytes, to)
-			if err != nil {
+			if err != nil && len(bodyBytes) > 0 {
 				canlog.CanError(err)
+				return errorReturn{err: NewProblem(http.StatusBadRequest, "invalid json body")}, http.StatusOK
 			}
 		}
+		if receiver {
+			callerIn[i] = pp.newReceiver(invoker, callerIn[i], uriRequestValue, request)
+		}
 
 	}
 	return call(*invoker.Method, callerIn)
@@ -573,14 +731,21 @@ func newValue(typ reflect.Type) reflect.Value {
 }
 
 // 执行函数
+// 最后一个返回值是error时，不为nil则返回errorReturn，否则使用第一个返回值
 func call(m reflect.Method, values []reflect.Value) (interface{}, int) {
-	// todo 是否支持无出参的函数
+	// 无出参的函数，认为已经在函数内完成了响应
 	vs := m.Func.Call(values)
+	if n := len(vs); n > 0 && m.Type.Out(n-1) == errorType {
+		if err, _ := vs[n-1].Interface().(error); err != nil {
+			return errorReturn{err: err}, http.StatusOK
+		}
+		vs = vs[:n-1]
+	}
 	if len(vs) == 0 {
-		return nil, http.StatusMethodNotAllowed
+		return nil, http.StatusOK
 	}
 	if !vs[0].IsValid() {
-		return nil, http.StatusMethodNotAllowed
+		return nil, http.StatusOK
 	}
 	if vs[0].Kind() == reflect.Ptr || vs[0].Kind() == reflect.Interface {
 		if vs[0].Elem().IsValid() {
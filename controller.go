@@ -21,6 +21,8 @@ import (
 type WebRequest struct {
 	http.ResponseWriter
 	*http.Request
+	// invoker 匹配到的处理函数
+	invoker *Invoker
 }
 
 /*
@@ -108,7 +110,7 @@ func newContext(request *WebRequest) *uriImpl {
 	return &uriImpl{request: request}
 }
 
-// todo  可以根据路由的uri/方法名等自动查找tpl
+// ModelView 使用模板渲染Model，Tpl为空时根据结构体名和方法名查找模板，见 SetTplResolver
 type ModelView struct {
 	Tpl   string
 	Model interface{}
@@ -0,0 +1,229 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"bytes"
+	"encoding/gob"
+	"reflect"
+	"sync"
+)
+
+type (
+	// bindPlan 处理函数的参数绑定计划，每个处理函数只在构建路由时计算一次
+	// 请求时只按照计划赋值，不再遍历结构体和计算字段名
+	bindPlan struct {
+		params []*paramPlan
+		// entities 参数中用到的cookie/session/header，没有用到的不需要解析
+		entities map[entityType]bool
+	}
+
+	paramPlan struct {
+		typ reflect.Type
+		// isURI 参数的类型就是cango.URI
+		isURI bool
+		// uriField 参数中可以赋值的URI字段，没有时为nil
+		uriField []int
+		// ctor 参数实现了Constructor
+		ctor      bool
+		ctorField []int
+		fields    []*bindField
+	}
+
+	// bindField 一个需要从请求中赋值的字段，和setValue的规则一致
+	bindField struct {
+		index  []int
+		names  []string
+		et     entityType
+		caster Caster
+		// slice 为true时caster用于切片的元素，caster可能为nil
+		slice bool
+	}
+)
+
+var cacheBindPlan sync.Map
+
+// bindPlanOf 返回函数类型的参数绑定计划，同一个函数类型只计算一次
+func bindPlanOf(fnType reflect.Type) *bindPlan {
+	if plan, ok := cacheBindPlan.Load(fnType); ok {
+		return plan.(*bindPlan)
+	}
+	plan := &bindPlan{entities: map[entityType]bool{}}
+	for i := 0; i < fnType.NumIn(); i++ {
+		pp := newParamPlan(fnType.In(i))
+		for _, f := range pp.fields {
+			plan.entities[f.et] = true
+		}
+		plan.params = append(plan.params, pp)
+	}
+	actual, _ := cacheBindPlan.LoadOrStore(fnType, plan)
+	return actual.(*bindPlan)
+}
+
+func newParamPlan(typ reflect.Type) *paramPlan {
+	pp := &paramPlan{typ: typ, isURI: typ == uriType, ctor: typ.Implements(constructorType)}
+	// 使用一个新的值来计算，和请求时newValue得到的值状态一致
+	rv := value(newValue(typ))
+	if rv.Kind() != reflect.Struct {
+		return pp
+	}
+	if typ.Implements(uriType) {
+		pp.uriField = settableField(rv, uriName)
+	}
+	if pp.ctor {
+		pp.ctorField = settableField(rv, constructorTypeName)
+	}
+	pp.fields = compileFields(rv, nil, fieldTagNames)
+	return pp
+}
+
+// settableField 返回可以赋值的字段的位置，经过nil指针或者不可赋值时返回nil
+func settableField(rv reflect.Value, name string) []int {
+	sf, ok := rv.Type().FieldByName(name)
+	if !ok {
+		return nil
+	}
+	f, err := rv.FieldByIndexErr(sf.Index)
+	if err != nil || !f.CanSet() {
+		return nil
+	}
+	return sf.Index
+}
+
+func compileFields(rv reflect.Value, prefix []int, filedName func(field reflect.StructField) ([]string, entityType)) (fields []*bindField) {
+	for i := 0; i < rv.NumField(); i++ {
+		index := append(append([]int{}, prefix...), i)
+		f := rv.Field(i)
+		// 新的值中指针都为nil，不会被赋值
+		if f.Kind() == reflect.Ptr {
+			continue
+		}
+		if f.Kind() == reflect.Struct && f.Type() != timeType {
+			fields = append(fields, compileFields(f, index, filedName)...)
+		}
+		if !f.CanSet() {
+			continue
+		}
+		bf := &bindField{index: index}
+		if f.Kind() == reflect.Slice {
+			bf.slice = true
+			bf.caster = casterMap[casterKind(f.Type().Elem())]
+		} else if caster, ok := casterMap[casterKind(f.Type())]; ok {
+			bf.caster = caster
+		} else {
+			continue
+		}
+		bf.names, bf.et = filedName(rv.Type().Field(i))
+		fields = append(fields, bf)
+	}
+	return fields
+}
+
+func casterKind(typ reflect.Type) reflect.Kind {
+	if typ == timeType {
+		return timeTypeKind
+	}
+	return typ.Kind()
+}
+
+// newArg 按照计划生成参数并赋值
+func (pp *paramPlan) newArg(uri reflect.Value, request *WebRequest, holder func(string, entityType) *entityValue) reflect.Value {
+	arg := pp.bindArg(uri, holder)
+	if !pp.isURI {
+		pp.construct(arg, value(arg), request)
+	}
+	return arg
+}
+
+// bindArg 生成参数并绑定请求中的值，不调用Construct
+func (pp *paramPlan) bindArg(uri reflect.Value, holder func(string, entityType) *entityValue) reflect.Value {
+	arg := newValue(pp.typ)
+	if pp.isURI {
+		arg.Set(uri)
+		return arg
+	}
+	rv := value(arg)
+	if pp.uriField != nil {
+		rv.FieldByIndex(pp.uriField).Set(uri)
+	}
+	for _, bf := range pp.fields {
+		bf.bind(rv.FieldByIndex(bf.index), holder)
+	}
+	return arg
+}
+
+// newReceiver 复制控制器的原型做为接收者，再把bound中绑定了请求的值的字段复制过来
+// 注入的依赖和原型上预设的字段不被请求修改
+func (pp *paramPlan) newReceiver(invoker *Invoker, bound, uri reflect.Value, request *WebRequest) reflect.Value {
+	arg := newValue(pp.typ)
+	rv := value(arg)
+	if invoker.receiver.IsValid() {
+		rv.Set(value(invoker.receiver))
+	}
+	bv := value(bound)
+	for _, i := range invoker.bindable {
+		rv.Field(i).Set(bv.Field(i))
+	}
+	if pp.uriField != nil {
+		rv.FieldByIndex(pp.uriField).Set(uri)
+	}
+	pp.construct(arg, rv, request)
+	return arg
+}
+
+// TODO redesign 这个接口
+func (pp *paramPlan) construct(arg, rv reflect.Value, request *WebRequest) {
+	if !pp.ctor {
+		return
+	}
+	if pp.ctorField != nil {
+		rv.FieldByIndex(pp.ctorField).Set(valueOfEmptyConstructor)
+	}
+	addr(arg).Interface().(Constructor).Construct(request)
+}
+
+func (bf *bindField) bind(f reflect.Value, holder func(string, entityType) *entityValue) {
+	if !bf.slice {
+		for _, name := range bf.names {
+			if v := holder(name, bf.et); v != nil {
+				switch v.enc {
+				case stringFlag:
+					f.Set(bf.caster(v.value.(string)))
+				case strSliceFlag:
+					f.Set(bf.caster(v.value.([]string)[0]))
+				case gobBytes:
+					_ = gob.NewDecoder(bytes.NewReader(v.value.([]byte))).DecodeValue(f)
+				}
+			}
+		}
+		return
+	}
+	for _, key := range bf.names {
+		if v := holder(key, bf.et); v != nil {
+			values, _ := v.value.([]string)
+			if len(values) == 0 {
+				continue
+			}
+			slice := reflect.MakeSlice(f.Type(), len(values), len(values))
+			if bf.caster != nil {
+				for idx, vs := range values {
+					slice.Index(idx).Set(bf.caster(vs))
+				}
+			}
+			f.Set(slice)
+			break
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type planParam struct {
+	URI      `value:"/plan/{id}"`
+	Id       int
+	UserName string
+	Tags     []string
+	Ages     []int
+	Token    string    `header:"X-Token"`
+	Birthday time.Time `cookie:"birth"`
+	School
+	Next   *School
+	hidden string
+}
+
+func planHolder(now time.Time) func(string, entityType) *entityValue {
+	form := map[string][]string{
+		"userName": {"cango"},
+		"tags":     {"a", "b"},
+		"ages":     {"1", "2"},
+		"isGood":   {"true"},
+		"hidden":   {"x"},
+	}
+	return func(key string, et entityType) *entityValue {
+		switch et {
+		case headerEntity:
+			if key == "X-Token" {
+				return &entityValue{enc: stringFlag, key: key, value: "t"}
+			}
+		case cookieEntity:
+			if key == "birth" {
+				return &entityValue{enc: stringFlag, key: key, value: now.Format(longSimpleTimeFormat)}
+			}
+		case defaultEntity:
+			if key == "id" {
+				return &entityValue{enc: stringFlag, key: key, value: "7"}
+			}
+			if v, ok := form[key]; ok {
+				return &entityValue{enc: strSliceFlag, key: key, value: v}
+			}
+		}
+		return nil
+	}
+}
+
+func Test_bindPlan(t *testing.T) {
+	now := time.Now()
+	holder := planHolder(now)
+	typ := reflect.TypeOf(planParam{})
+
+	want := newValue(typ)
+	doDecode(addr(want), holder, fieldTagNames)
+
+	pp := newParamPlan(typ)
+	got := pp.newArg(reflect.ValueOf(newContext(nil)), nil, holder)
+	got.FieldByName(uriName).Set(reflect.Zero(uriType))
+
+	if !reflect.DeepEqual(got.Interface(), want.Interface()) {
+		t.Errorf("newArg() = %+v, want %+v", got.Interface(), want.Interface())
+	}
+	if p := got.Interface().(planParam); p.Id != 7 || p.UserName != "cango" || len(p.Ages) != 2 || p.Token != "t" || !p.IsGood || p.hidden != "" {
+		t.Errorf("newArg() = %+v", p)
+	}
+	if pp.uriField == nil {
+		t.Errorf("newParamPlan() uriField is nil")
+	}
+
+	plan := bindPlanOf(reflect.TypeOf(func(planParam) {}))
+	if plan != bindPlanOf(reflect.TypeOf(func(planParam) {})) {
+		t.Errorf("bindPlanOf() is not cached")
+	}
+	if !plan.entities[headerEntity] || !plan.entities[cookieEntity] || plan.entities[sessionEntity] {
+		t.Errorf("bindPlanOf() entities = %v", plan.entities)
+	}
+}
+
+type planCtrl struct {
+	URI `value:"/plan"`
+}
+
+func (c *planCtrl) User(ps planParam) interface{} {
+	return Content{String: ps.UserName}
+}
+
+func Benchmark_doDecode(b *testing.B) {
+	holder := planHolder(time.Now())
+	typ := reflect.TypeOf(planParam{})
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		v := newValue(typ)
+		doDecode(addr(v), holder, fieldTagNames)
+	}
+}
+
+func Benchmark_bindPlan(b *testing.B) {
+	holder := planHolder(time.Now())
+	pp := newParamPlan(reflect.TypeOf(planParam{}))
+	uri := reflect.ValueOf(newContext(nil))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		pp.newArg(uri, nil, holder)
+	}
+}
+
+func BenchmarkCan_ServeHTTP_bind(b *testing.B) {
+	can := NewCan().Route(&planCtrl{})
+	can.build()
+	req := httptest.NewRequest(http.MethodGet, "/plan/plan/7?userName=cango&tags=a&tags=b", nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		can.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
@@ -16,6 +16,7 @@ package cango
 import (
 	"errors"
 	"net/http"
+	"sort"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -42,32 +43,42 @@ type (
 type (
 	canDispatcher struct {
 		muxSlice []dispatcher
-		fastMux  *fastDispatcher
+		treeMux  *treeDispatcher
 		mapMux   *mapDispatcher
+		// hosts 限定了host的路由，优先于不限定host的路由匹配
+		hosts []*hostDispatcher
+		// errs 记录无法解析的host pattern
+		errs []string
 	}
 	canForwarder struct {
 		mapForwarder  forwarder
-		fastForwarder forwarder
+		treeForwarder forwarder
 	}
 )
 
 func newCanMux() *canDispatcher {
 	mm := newMapDispatcher()
-	fm := newFastDispatcher()
+	tm := newTreeDispatcher()
 	return &canDispatcher{
 		mapMux:   mm,
-		fastMux:  fm,
-		muxSlice: []dispatcher{mm, fm},
+		treeMux:  tm,
+		muxSlice: []dispatcher{mm, tm},
 	}
 }
 
+// patternErrors 返回构建时无法解析的pattern，包括各个host下的
+func (m *canDispatcher) patternErrors() []string {
+	errs := append(append([]string{}, m.errs...), m.treeMux.errs...)
+	for _, hd := range m.hosts {
+		errs = append(errs, hd.patternErrors()...)
+	}
+	return errs
+}
+
 func isVarPattern(path string) bool {
 	return strings.Contains(path, "{") || strings.Contains(path, "*")
 }
 
-// Gin's path parameters pattern is /:name, Cango's is /{name}
-var replacer = strings.NewReplacer("{", ":", "}", "")
-
 type JSON struct {
 	Data interface{}
 }
@@ -105,16 +116,13 @@ func writeContentType(w http.ResponseWriter, value []string) {
 	}
 }
 
-func (m *canDispatcher) Gins() (ghs []*GinHandler) {
+// Gins 生成gin的处理函数，renderError用于处理 (T, error) 中返回的error，和cango自身的处理一致
+func (m *canDispatcher) Gins(renderError func(w http.ResponseWriter, r *http.Request, err error)) (ghs []*GinHandler) {
 	for _, forwarder := range m.mapMux.forwarders {
 		for _, pattern := range forwarder.patternMap {
 			gh := &GinHandler{
-				Url: func() string {
-					if strings.Contains(pattern.path, "{") {
-						return replacer.Replace(pattern.path)
-					}
-					return pattern.path
-				}(),
+				// Gin's path parameters pattern is /:name, Cango's is /{name}
+				Url: ginPath(pattern.path),
 				Handle: func(ctx *gin.Context) {
 					handleReturn, code := serve(m, &WebRequest{
 						ResponseWriter: ctx.Writer,
@@ -129,6 +137,14 @@ func (m *canDispatcher) Gins() (ghs []*GinHandler) {
 						ctx.Redirect(code, hr.Url)
 					case ContentWithCode:
 						ctx.String(hr.Code, hr.String)
+					case Problem:
+						writeProblem(ctx.Writer, ctx.Request, hr)
+					case EventStream:
+						serveEvents(ctx.Writer, ctx.Request, hr)
+					case WebSocket:
+						serveWebSocket(ctx.Writer, ctx.Request, hr)
+					case errorReturn:
+						renderError(ctx.Writer, ctx.Request, hr.err)
 					default:
 						ctx.Render(code, JSON{Data: handleReturn})
 					}
@@ -142,21 +158,88 @@ func (m *canDispatcher) Gins() (ghs []*GinHandler) {
 }
 
 func (m *canDispatcher) NewForwarder(name string, invoker *Invoker) forwarder {
-	return &canForwarder{mapForwarder: m.mapMux.NewForwarder(name, invoker), fastForwarder: m.fastMux.NewForwarder(name, invoker)}
+	return &canForwarder{mapForwarder: m.mapMux.NewForwarder(name, invoker), treeForwarder: m.treeMux.NewForwarder(name, invoker)}
 }
 func (m *canDispatcher) Match(req *http.Request) matcher {
+	allow := map[string]bool{}
+	if len(m.hosts) > 0 {
+		labels := strings.Split(hostOnly(req.Host), ".")
+		for _, hd := range m.hosts {
+			vars, ok := hd.matchHost(labels)
+			if !ok {
+				continue
+			}
+			cm := hd.canDispatcher.Match(req)
+			if cm.Error() == nil {
+				return &hostMatcher{matcher: cm, vars: vars}
+			}
+			if mna, ok := cm.Error().(*methodNotAllowedError); ok {
+				for _, method := range mna.allow {
+					allow[method] = true
+				}
+			}
+		}
+	}
 	for _, v := range m.muxSlice {
-		if cm := v.Match(req); cm.Error() == nil {
+		cm := v.Match(req)
+		if cm.Error() == nil {
 			return cm
 		}
+		if mna, ok := cm.Error().(*methodNotAllowedError); ok {
+			for _, method := range mna.allow {
+				allow[method] = true
+			}
+		}
+	}
+	if len(allow) > 0 {
+		return &mapMatcher{err: newMethodNotAllowedError(allow)}
 	}
 	return &mapMatcher{err: errors.New("can dispatch can't find the path")}
 }
 
+// methodNotAllowedError 路径存在，但是没有注册请求的方法
+type methodNotAllowedError struct {
+	allow []string
+}
+
+func newMethodNotAllowedError(allow map[string]bool) *methodNotAllowedError {
+	mna := &methodNotAllowedError{}
+	for method := range allow {
+		mna.allow = append(mna.allow, method)
+	}
+	sort.Strings(mna.allow)
+	return mna
+}
+
+func (e *methodNotAllowedError) allows(method string) bool {
+	for _, m := range e.allow {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// implicit 加上自动支持的HEAD和OPTIONS方法
+func (e *methodNotAllowedError) implicit() []string {
+	allow := map[string]bool{http.MethodOptions: true}
+	for _, m := range e.allow {
+		allow[m] = true
+	}
+	if allow[http.MethodGet] {
+		allow[http.MethodHead] = true
+	}
+	return newMethodNotAllowedError(allow).allow
+}
+
+func (e *methodNotAllowedError) Error() string {
+	return "method not allowed, allow " + strings.Join(e.allow, ", ")
+}
+
 func (m *canForwarder) PathMethods(path string, ms ...string) {
 	m.mapForwarder.PathMethods(path, ms...)
 	if isVarPattern(path) {
-		m.fastForwarder.PathMethods(path, ms...)
+		m.treeForwarder.PathMethods(path, ms...)
 	}
 }
 
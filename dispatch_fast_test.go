@@ -16,10 +16,11 @@ package cango
 import (
 	"errors"
 	"net/http"
-	"path/filepath"
 	"strings"
 )
 
+// fastDispatcher 是早期复制-剪枝方式的实现，已经被treeDispatcher替代
+// 只保留在测试中，用于基准测试对比
 type (
 	fastDispatcher struct {
 		forwarders   map[string]*fastForwarder
@@ -52,12 +53,6 @@ type (
 	}
 )
 
-type word struct {
-	key        string
-	isVar      bool
-	isWildcard bool
-}
-
 var _ = forwarder(&fastForwarder{})
 
 func newFastDispatcher() *fastDispatcher {
@@ -105,6 +100,10 @@ func (fm *fastDispatcher) doMatch(method, url string) *fastMatcher {
 				}
 			}
 
+			if k >= len(pattern.words) {
+				delete(searchMap, key)
+				continue
+			}
 			// 如果是变量，肯定符合
 			if pattern.words[k].isVar {
 				continue
@@ -131,24 +130,23 @@ func (fm *fastDispatcher) doMatch(method, url string) *fastMatcher {
 		if pattern == nil {
 			return nil
 		}
-		fm := &fastMatcher{
-			forwarder: pattern.forwarder,
-			vars:      make(map[string]string, len(pattern.varIdx)),
-		}
-		if len(pattern.varIdx) > 0 {
-			for _, idx := range pattern.varIdx {
-				fm.vars[pattern.words[idx].key] = elements[idx]
-			}
-		}
-		return fm
+		return pattern.matcher(elements)
 	default:
 		// 找到多个
-		// todo 选最接近的
-		// todo 这里可以随机选一个
-		// panic("multi should handle")
 		return nil
 	}
 }
+
+func (fp *fastPatten) matcher(elements []string) *fastMatcher {
+	fm := &fastMatcher{
+		forwarder: fp.forwarder,
+		vars:      make(map[string]string, len(fp.varIdx)),
+	}
+	for _, idx := range fp.varIdx {
+		fm.vars[fp.words[idx].key] = elements[idx]
+	}
+	return fm
+}
 func (fm *fastDispatcher) Match(req *http.Request) matcher {
 	cm := fm.doMatch(req.Method, req.URL.Path)
 	if cm == nil {
@@ -161,7 +159,7 @@ func (fr *fastForwarder) PathMethods(path string, ms ...string) {
 	patten, ok := fr.patternMap[path]
 	if !ok {
 		patten = &fastPatten{forwarder: fr, pattern: path, methodMap: map[string]bool{}}
-		patten.words, patten.varIdx, patten.isWildcard = elementsToWords(parsePath(path))
+		patten.words, patten.varIdx, patten.isWildcard, _ = elementsToWords(parsePattern(path))
 		if patten.isWildcard {
 			ss := strings.Split(path, "*")
 			patten.wildcardLeft = ss[0]
@@ -192,44 +190,3 @@ func (fm *fastMatcher) Forwarder() forwarder {
 func (fm *fastMatcher) GetVars() map[string]string {
 	return fm.vars
 }
-
-func elementsToWords(elements []string) ([]word, []int, bool) {
-	words := make([]word, len(elements))
-	idx := make([]int, len(elements))
-	isWildcard := false
-	j := 0
-	for i, elem := range elements {
-		if elem == "*" {
-			words[i] = word{isWildcard: true}
-			isWildcard = true
-			continue
-		}
-		// todo 如果这个word就是{}呢？
-		// todo 也就是说地址是/a/{}/b/c 这种的话不会被当做变量
-		// todo 如果真实需要注册的地址就是/a/{name}/b/c 应该怎么办？
-		if strings.HasPrefix(elem, "{") && strings.HasSuffix(elem, "}") {
-			words[i] = word{key: elem[1 : len(elem)-1], isVar: true}
-			idx[j] = i
-			j++
-			continue
-		}
-		words[i] = word{key: elem, isVar: false}
-	}
-	return words, idx[0:j], isWildcard
-}
-
-func parsePath(url string) []string {
-	// todo : clean & split in only one loop
-	url = filepath.Clean(url)
-	// todo : 如果是 /a/b/c/ 是不是要等价于 /a/b/c 呢？
-	if url == "" || url == "/" {
-		return []string{"/"}
-	}
-	// todo .这个分隔符应该是最后一个才需要
-	return strings.FieldsFunc(url, func(r rune) bool {
-		if r == '/' || r == '.' {
-			return true
-		}
-		return false
-	})
-}
@@ -0,0 +1,124 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"net"
+	"strings"
+)
+
+// hostDispatcher 一个host模式下的路由，host按.切分成段，每段可以是
+// 静态的（忽略大小写）、变量{tenant}、带约束的变量{id:int}、段内通配符api-*或者任意一段*
+type (
+	hostDispatcher struct {
+		*canDispatcher
+		pattern string
+		words   []word
+	}
+	hostMatcher struct {
+		matcher
+		vars map[string]string
+	}
+)
+
+// host 返回pattern对应的分发器，pattern为空时返回自己
+// host按具体程度排序，越具体的越先匹配
+func (m *canDispatcher) host(pattern string) *canDispatcher {
+	pattern = hostOnly(pattern)
+	if pattern == "" {
+		return m
+	}
+	for _, hd := range m.hosts {
+		if hd.pattern == pattern {
+			return hd.canDispatcher
+		}
+	}
+	words, _, _, err := elementsToWords(parsePattern(pattern))
+	if err != nil {
+		// 不能解析的host不参与匹配，返回一个不挂载的分发器，错误在检查路由时返回
+		m.errs = append(m.errs, "host "+pattern+": "+err.Error())
+		return newCanMux()
+	}
+	// host不区分大小写，静态部分统一转成小写
+	for i, w := range words {
+		if w.isVar {
+			continue
+		}
+		words[i].key = strings.ToLower(w.key)
+		for j, part := range w.glob {
+			words[i].glob[j] = strings.ToLower(part)
+		}
+	}
+	hd := &hostDispatcher{canDispatcher: newCanMux(), pattern: pattern, words: words}
+	idx := len(m.hosts)
+	for i, v := range m.hosts {
+		if moreSpecific(words, v.words) {
+			idx = i
+			break
+		}
+	}
+	m.hosts = append(m.hosts, nil)
+	copy(m.hosts[idx+1:], m.hosts[idx:])
+	m.hosts[idx] = hd
+	return hd.canDispatcher
+}
+
+// matchHost 判断请求的host是否符合，返回host中的变量
+func (hd *hostDispatcher) matchHost(labels []string) (map[string]string, bool) {
+	if len(labels) != len(hd.words) {
+		return nil, false
+	}
+	vars := map[string]string{}
+	for i, w := range hd.words {
+		label := labels[i]
+		switch {
+		case w.isWildcard:
+		case w.isVar:
+			if w.check != nil && !w.check(label) {
+				return nil, false
+			}
+			vars[w.key] = label
+		case len(w.glob) > 0:
+			if !globMatch(w.glob, strings.ToLower(label)) {
+				return nil, false
+			}
+		default:
+			if !strings.EqualFold(w.key, label) {
+				return nil, false
+			}
+		}
+	}
+	return vars, true
+}
+
+// hostOnly 去掉host中的端口
+func hostOnly(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// GetVars host中的变量和路径中的变量合并，同名时路径中的优先
+func (hm *hostMatcher) GetVars() map[string]string {
+	vars := make(map[string]string, len(hm.vars))
+	for k, v := range hm.vars {
+		vars[k] = v
+	}
+	for k, v := range hm.matcher.GetVars() {
+		vars[k] = v
+	}
+	return vars
+}
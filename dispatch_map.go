@@ -64,6 +64,18 @@ func (m *mapDispatcher) Match(req *http.Request) matcher {
 			return &mapMatcher{innerRouter: r}
 		}
 	}
+	// 路径存在，但是方法不匹配
+	if len(names) > 0 {
+		allow := map[string]bool{}
+		for _, name := range names {
+			if r, ok := m.forwarders[name]; ok {
+				for method := range r.patternMap[req.URL.Path].methods {
+					allow[method] = true
+				}
+			}
+		}
+		return &mapMatcher{err: newMethodNotAllowedError(allow)}
+	}
 	return &mapMatcher{err: errors.New("map dispatch can't find the path")}
 }
 
@@ -0,0 +1,360 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cango
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// treeDispatcher 使用前缀树来匹配带有变量或者通配符的路由
+// 每个节点对应路径中的一段，静态段、变量段{name}和通配符*分别存放，
+// 匹配时只需要沿着树走一遍，不需要在每次请求时遍历所有的pattern
+type (
+	treeDispatcher struct {
+		root       *treeNode
+		forwarders map[string]*treeForwarder
+		// errs 记录无法解析的pattern，这些pattern不会加入路由树
+		errs []string
+	}
+	treeForwarder struct {
+		innerMux   *treeDispatcher
+		name       string
+		invoker    *Invoker
+		pattens    []*treePatten
+		patternMap map[string]*treePatten
+	}
+	treePatten struct {
+		forwarder *treeForwarder
+		pattern   string
+		words     []word
+		varIdx    []int
+		methodMap map[string]bool
+	}
+	treeNode struct {
+		statics map[string]*treeNode
+		// 段内通配符节点，如 img_*
+		globs []*treeNode
+		// 有约束的变量节点排在前面，没有约束的排在最后
+		params   []*treeNode
+		wildcard *treeNode
+		// 命名的剩余路径变量节点{path...}，只会出现在最后
+		catchAll *treeNode
+		// 段内通配符节点的原始段和切分后的各部分
+		globKey string
+		glob    []string
+		// 变量节点的约束
+		constraint string
+		check      func(string) bool
+		// 在此节点结束的pattern，按注册顺序排列
+		pattens []*treePatten
+	}
+	treeMatcher struct {
+		err       error
+		forwarder *treeForwarder
+		vars      map[string]string
+	}
+)
+
+var _ = dispatcher(&treeDispatcher{})
+var _ = forwarder(&treeForwarder{})
+var _ = matcher(&treeMatcher{})
+
+func newTreeDispatcher() *treeDispatcher {
+	return &treeDispatcher{root: &treeNode{}, forwarders: map[string]*treeForwarder{}}
+}
+
+func (td *treeDispatcher) NewForwarder(name string, invoker *Invoker) forwarder {
+	tf, ok := td.forwarders[name]
+	if ok {
+		return tf
+	}
+	tf = &treeForwarder{innerMux: td, name: name, invoker: invoker, patternMap: map[string]*treePatten{}}
+	td.forwarders[name] = tf
+	return tf
+}
+
+func (td *treeDispatcher) doMatch(method, url string) *treeMatcher {
+	elements := parsePath(url)
+	patten, pos := td.root.lookup(method, elements, segmentEnds(url), 0, make([]int, 0, len(elements)))
+	if patten == nil {
+		return nil
+	}
+	tm := &treeMatcher{forwarder: patten.forwarder, vars: make(map[string]string, len(patten.varIdx))}
+	for _, idx := range patten.varIdx {
+		if patten.words[idx].isCatchAll {
+			tm.vars[patten.words[idx].key] = pathRemainder(url, pos[idx])
+			continue
+		}
+		tm.vars[patten.words[idx].key] = elements[pos[idx]]
+	}
+	return tm
+}
+
+func (td *treeDispatcher) Match(req *http.Request) matcher {
+	tm := td.doMatch(req.Method, req.URL.Path)
+	if tm == nil {
+		allow := map[string]bool{}
+		td.root.allowed(parsePath(req.URL.Path), segmentEnds(req.URL.Path), 0, allow)
+		if len(allow) > 0 {
+			return &treeMatcher{err: newMethodNotAllowedError(allow)}
+		}
+		return &treeMatcher{err: errors.New("tree dispatch can't find the path")}
+	}
+	return tm
+}
+
+// insert 把words挂到树上，返回最后一个word所在的节点
+func (n *treeNode) insert(words []word) *treeNode {
+	for _, w := range words {
+		switch {
+		case w.isWildcard:
+			if n.wildcard == nil {
+				n.wildcard = &treeNode{}
+			}
+			n = n.wildcard
+		case w.isCatchAll:
+			if n.catchAll == nil {
+				n.catchAll = &treeNode{}
+			}
+			n = n.catchAll
+		case len(w.glob) > 0:
+			n = n.globChild(w)
+		case w.isVar:
+			n = n.paramChild(w)
+		default:
+			if n.statics == nil {
+				n.statics = map[string]*treeNode{}
+			}
+			child, ok := n.statics[w.key]
+			if !ok {
+				child = &treeNode{}
+				n.statics[w.key] = child
+			}
+			n = child
+		}
+	}
+	return n
+}
+
+// globChild 取得相同的段内通配符节点，没有则新建
+func (n *treeNode) globChild(w word) *treeNode {
+	for _, child := range n.globs {
+		if child.globKey == w.key {
+			return child
+		}
+	}
+	child := &treeNode{globKey: w.key, glob: w.glob}
+	n.globs = append(n.globs, child)
+	return child
+}
+
+// paramChild 取得约束相同的变量节点，没有则新建
+func (n *treeNode) paramChild(w word) *treeNode {
+	for _, child := range n.params {
+		if child.constraint == w.constraint {
+			return child
+		}
+	}
+	child := &treeNode{constraint: w.constraint, check: w.check}
+	if w.constraint == "" {
+		n.params = append(n.params, child)
+		return child
+	}
+	// 插入到没有约束的节点之前
+	idx := len(n.params)
+	if idx > 0 && n.params[idx-1].constraint == "" {
+		idx--
+	}
+	n.params = append(n.params, nil)
+	copy(n.params[idx+1:], n.params[idx:])
+	n.params[idx] = child
+	return child
+}
+
+// lookup 从第i段开始深度优先查找，依次尝试静态段、段内通配符、变量段、通配符和剩余路径变量
+// pos 记录每一个word匹配到的element下标，用于取出路径变量的值
+// ends 为每一段所在的/段结束的下标，段内通配符匹配整个/段，见 segmentEnds
+func (n *treeNode) lookup(method string, elements []string, ends []int, i int, pos []int) (*treePatten, []int) {
+	if i == len(elements) {
+		if p := n.methodPatten(method); p != nil {
+			return p, pos
+		}
+		// 剩余路径变量可以匹配空路径
+		if n.catchAll != nil {
+			if p := n.catchAll.methodPatten(method); p != nil {
+				return p, append(pos, i)
+			}
+		}
+		return nil, pos
+	}
+	if child, ok := n.statics[elements[i]]; ok {
+		if p, ps := child.lookup(method, elements, ends, i+1, append(pos, i)); p != nil {
+			return p, ps
+		}
+	}
+	if raw, end, ok := rawSegment(elements, ends, i); ok {
+		for _, child := range n.globs {
+			if !globMatch(child.glob, raw) {
+				continue
+			}
+			if p, ps := child.lookup(method, elements, ends, end, append(pos, i)); p != nil {
+				return p, ps
+			}
+		}
+	}
+	for _, child := range n.params {
+		if child.check != nil && !child.check(elements[i]) {
+			continue
+		}
+		if p, ps := child.lookup(method, elements, ends, i+1, append(pos, i)); p != nil {
+			return p, ps
+		}
+	}
+	if n.wildcard != nil {
+		// 通配符至少匹配一段，先尝试匹配最少的段数，让后面还有路径的pattern优先
+		for j := i + 1; j <= len(elements); j++ {
+			if p, ps := n.wildcard.lookup(method, elements, ends, j, append(pos, i)); p != nil {
+				return p, ps
+			}
+		}
+	}
+	if n.catchAll != nil {
+		if p := n.catchAll.methodPatten(method); p != nil {
+			return p, append(pos, i)
+		}
+	}
+	return nil, pos
+}
+
+// allowed 收集所有能匹配路径的pattern上注册的方法，只在匹配失败时使用
+func (n *treeNode) allowed(elements []string, ends []int, i int, allow map[string]bool) {
+	if i == len(elements) {
+		for _, p := range n.pattens {
+			for m := range p.methodMap {
+				allow[m] = true
+			}
+		}
+		if n.catchAll != nil {
+			n.catchAll.allowed(elements, ends, i, allow)
+		}
+		return
+	}
+	if child, ok := n.statics[elements[i]]; ok {
+		child.allowed(elements, ends, i+1, allow)
+	}
+	if raw, end, ok := rawSegment(elements, ends, i); ok {
+		for _, child := range n.globs {
+			if globMatch(child.glob, raw) {
+				child.allowed(elements, ends, end, allow)
+			}
+		}
+	}
+	for _, child := range n.params {
+		if child.check == nil || child.check(elements[i]) {
+			child.allowed(elements, ends, i+1, allow)
+		}
+	}
+	if n.wildcard != nil {
+		for j := i + 1; j <= len(elements); j++ {
+			n.wildcard.allowed(elements, ends, j, allow)
+		}
+	}
+	if n.catchAll != nil {
+		n.catchAll.allowed(elements, ends, len(elements), allow)
+	}
+}
+
+// rawSegment 从第i段开始的整个/段，i不是/段的开始时返回false
+func rawSegment(elements []string, ends []int, i int) (string, int, bool) {
+	if i >= len(ends) || (i > 0 && ends[i-1] != i) {
+		return "", 0, false
+	}
+	return strings.Join(elements[i:ends[i]], "."), ends[i], true
+}
+
+func (n *treeNode) methodPatten(method string) *treePatten {
+	for _, p := range n.pattens {
+		if p.methodMap[method] {
+			return p
+		}
+	}
+	return nil
+}
+
+// wordRank 静态段最具体，其次是段内通配符、有约束的变量段、没有约束的变量段、通配符，最后是剩余路径变量
+func wordRank(w word) int {
+	switch {
+	case w.isCatchAll:
+		return 5
+	case w.isWildcard:
+		return 4
+	case w.isVar && w.constraint == "":
+		return 3
+	case w.isVar:
+		return 2
+	case len(w.glob) > 0:
+		return 1
+	}
+	return 0
+}
+
+// moreSpecific 判断pattern a是否比b更具体
+// 从左往右逐段比较，排在前面的段按wordRank决定；前面都相同时，段数多的优先
+// treeNode.lookup 的查找顺序与这里的定义保持一致
+func moreSpecific(a, b []word) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		ra, rb := wordRank(a[i]), wordRank(b[i])
+		if ra != rb {
+			return ra < rb
+		}
+	}
+	return len(a) > len(b)
+}
+
+func (tf *treeForwarder) PathMethods(path string, ms ...string) {
+	patten, ok := tf.patternMap[path]
+	if !ok {
+		words, varIdx, _, err := elementsToWords(parseRoutePattern(path))
+		if err != nil {
+			tf.innerMux.errs = append(tf.innerMux.errs, tf.name+" "+path+": "+err.Error())
+			return
+		}
+		patten = &treePatten{forwarder: tf, pattern: path, methodMap: map[string]bool{}, words: words, varIdx: varIdx}
+		node := tf.innerMux.root.insert(patten.words)
+		node.pattens = append(node.pattens, patten)
+		tf.pattens = append(tf.pattens, patten)
+		tf.patternMap[path] = patten
+	}
+	for _, m := range ms {
+		patten.methodMap[m] = true
+	}
+}
+
+func (tf *treeForwarder) GetInvoker() *Invoker {
+	return tf.invoker
+}
+
+func (tm *treeMatcher) Error() error {
+	return tm.err
+}
+
+func (tm *treeMatcher) Forwarder() forwarder {
+	return tm.forwarder
+}
+
+func (tm *treeMatcher) GetVars() map[string]string {
+	return tm.vars
+}
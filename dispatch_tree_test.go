@@ -0,0 +1,176 @@
+package cango
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+func Test_treeDispatcher_doMatch(t *testing.T) {
+	td := newTreeDispatcher()
+	td.NewForwarder("user", nil).PathMethods("/user/{id}", http.MethodGet)
+	td.NewForwarder("userPost", nil).PathMethods("/user/{id}", http.MethodPost)
+	td.NewForwarder("book", nil).PathMethods("/user/{uid}/book/{bid}.json", http.MethodGet)
+	td.NewForwarder("static", nil).PathMethods("/static/*", http.MethodGet)
+	td.NewForwarder("html", nil).PathMethods("/page/*.html", http.MethodGet)
+	td.NewForwarder("all", nil).PathMethods("/*", http.MethodGet)
+
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		wantName string
+		wantVars map[string]string
+	}{
+		{name: "var", method: http.MethodGet, url: "/user/12", wantName: "user", wantVars: map[string]string{"id": "12"}},
+		{name: "method", method: http.MethodPost, url: "/user/12", wantName: "userPost", wantVars: map[string]string{"id": "12"}},
+		{name: "multi_var", method: http.MethodGet, url: "/user/1/book/2.json", wantName: "book", wantVars: map[string]string{"uid": "1", "bid": "2"}},
+		{name: "wildcard", method: http.MethodGet, url: "/static/css/a.css", wantName: "static", wantVars: map[string]string{}},
+		{name: "wildcard_middle", method: http.MethodGet, url: "/page/a/b.html", wantName: "html", wantVars: map[string]string{}},
+		{name: "wildcard_root", method: http.MethodGet, url: "/user/1/book", wantName: "all", wantVars: map[string]string{}},
+		{name: "root", method: http.MethodGet, url: "/", wantName: "all", wantVars: map[string]string{}},
+		{name: "not_found", method: http.MethodDelete, url: "/user/12", wantName: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := td.doMatch(tt.method, tt.url)
+			if tm == nil {
+				if tt.wantName != "" {
+					t.Errorf("doMatch() = nil, want %v", tt.wantName)
+				}
+				return
+			}
+			if tm.forwarder.name != tt.wantName {
+				t.Errorf("doMatch() name = %v, want %v", tm.forwarder.name, tt.wantName)
+			}
+			if !reflect.DeepEqual(tm.vars, tt.wantVars) {
+				t.Errorf("doMatch() vars = %v, want %v", tm.vars, tt.wantVars)
+			}
+		})
+	}
+}
+
+func benchmarkPatterns(n int) []string {
+	var paths []string
+	for i := 0; i < n; i++ {
+		paths = append(paths, fmt.Sprintf("/api/v1/resource%d/{id}", i), fmt.Sprintf("/api/v1/resource%d/{id}/items/{item}", i))
+	}
+	return paths
+}
+
+func BenchmarkFastDispatcher(b *testing.B) {
+	fd := newFastDispatcher()
+	for i, path := range benchmarkPatterns(300) {
+		fd.NewForwarder(fmt.Sprint(i), nil).PathMethods(path, http.MethodGet)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if fd.doMatch(http.MethodGet, "/api/v1/resource150/12/items/34") == nil {
+			b.Fatal("not match")
+		}
+	}
+}
+
+func BenchmarkTreeDispatcher(b *testing.B) {
+	td := newTreeDispatcher()
+	for i, path := range benchmarkPatterns(300) {
+		td.NewForwarder(fmt.Sprint(i), nil).PathMethods(path, http.MethodGet)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if td.doMatch(http.MethodGet, "/api/v1/resource150/12/items/34") == nil {
+			b.Fatal("not match")
+		}
+	}
+}
+
+func Test_treeDispatcher_priority(t *testing.T) {
+	patterns := []string{
+		"/user/{id}",
+		"/user/me.json",
+		"/user/{id}/profile",
+		"/user/{id}/{tab}",
+		"/user/me/{tab}",
+		"/user/*",
+		"/user/*/edit",
+		"/*",
+		"/file/{dir}/*",
+		"/file/*/{name}.txt",
+	}
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{url: "/user/12", want: "/user/{id}"},
+		{url: "/user/me.json", want: "/user/me.json"},
+		{url: "/user/me.xml", want: "/user/me/{tab}"},
+		{url: "/user/12/profile", want: "/user/{id}/profile"},
+		{url: "/user/12/books", want: "/user/{id}/{tab}"},
+		{url: "/user/me/profile", want: "/user/me/{tab}"},
+		{url: "/user/1/2/3", want: "/user/*"},
+		{url: "/user/1/2/edit", want: "/user/*/edit"},
+		{url: "/admin", want: "/*"},
+		{url: "/file/a/b.txt", want: "/file/{dir}/*"},
+		{url: "/file/a/b/c.txt", want: "/file/{dir}/*"},
+	}
+	orders := map[string][]string{"asc": patterns, "desc": nil}
+	for i := len(patterns) - 1; i >= 0; i-- {
+		orders["desc"] = append(orders["desc"], patterns[i])
+	}
+	for name, order := range orders {
+		td := newTreeDispatcher()
+		for _, path := range order {
+			td.NewForwarder(path, nil).PathMethods(path, http.MethodGet)
+		}
+		for _, tt := range tests {
+			t.Run(name+tt.url, func(t *testing.T) {
+				tm := td.doMatch(http.MethodGet, tt.url)
+				if tm == nil {
+					t.Fatalf("doMatch(%v) = nil, want %v", tt.url, tt.want)
+				}
+				if tm.forwarder.name != tt.want {
+					t.Errorf("doMatch(%v) = %v, want %v", tt.url, tm.forwarder.name, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func Test_treeDispatcher_sameShape(t *testing.T) {
+	// 形状完全相同的pattern，先注册的优先
+	td := newTreeDispatcher()
+	td.NewForwarder("first", nil).PathMethods("/item/{id}", http.MethodGet)
+	td.NewForwarder("second", nil).PathMethods("/item/{name}", http.MethodGet, http.MethodPost)
+	if tm := td.doMatch(http.MethodGet, "/item/1"); tm == nil || tm.forwarder.name != "first" || tm.vars["id"] != "1" {
+		t.Errorf("doMatch() = %+v, want first", tm)
+	}
+	if tm := td.doMatch(http.MethodPost, "/item/1"); tm == nil || tm.forwarder.name != "second" || tm.vars["name"] != "1" {
+		t.Errorf("doMatch() = %+v, want second", tm)
+	}
+}
+
+func Test_moreSpecific(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{a: "/user/me", b: "/user/{id}", want: true},
+		{a: "/user/{id}", b: "/user/*", want: true},
+		{a: "/user/{id}/a", b: "/user/{id}", want: true},
+		{a: "/user/*/a", b: "/user/*", want: true},
+		{a: "/user/{id}", b: "/user/me", want: false},
+		{a: "/user/{id}", b: "/user/{name}", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"|"+tt.b, func(t *testing.T) {
+			a, _, _, _ := elementsToWords(parsePath(tt.a))
+			b, _, _, _ := elementsToWords(parsePath(tt.b))
+			if got := moreSpecific(a, b); got != tt.want {
+				t.Errorf("moreSpecific() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
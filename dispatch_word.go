@@ -0,0 +1,307 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+package cango
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type word struct {
+	key        string
+	isVar      bool
+	isWildcard bool
+	// constraint 路径变量的约束，check 为对应的校验函数
+	constraint string
+	check      func(string) bool
+	// isCatchAll 形如{path...}，匹配剩余的所有路径
+	isCatchAll bool
+	// glob 段内通配符按*切分后的各部分，如 img_*.png 切分为 img_ 和 .png
+	glob []string
+}
+
+// 路径变量支持使用约束，形如 {id:int}、{slug:[a-z-]+}、{uuid:uuid}
+// 冒号后面是内置的类型名，不是内置类型名的都当做正则表达式
+var varConstraints = map[string]func(string) bool{
+	"int": func(s string) bool {
+		_, err := strconv.ParseInt(s, 10, 64)
+		return err == nil
+	},
+	"uint": func(s string) bool {
+		_, err := strconv.ParseUint(s, 10, 64)
+		return err == nil
+	},
+	"float": func(s string) bool {
+		_, err := strconv.ParseFloat(s, 64)
+		return err == nil
+	},
+	"uuid":  regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`).MatchString,
+	"alpha": regexp.MustCompile(`^[a-zA-Z]+$`).MatchString,
+}
+
+// newVarWord 解析{}中的内容，name:constraint 或者 name...，约束不是合法的正则时返回错误
+func newVarWord(inner string) (word, error) {
+	if strings.HasSuffix(inner, "...") {
+		return word{key: strings.TrimSuffix(inner, "..."), isVar: true, isCatchAll: true}, nil
+	}
+	idx := strings.Index(inner, ":")
+	if idx == -1 {
+		return word{key: inner, isVar: true}, nil
+	}
+	w := word{key: inner[:idx], isVar: true, constraint: inner[idx+1:]}
+	if fn, ok := varConstraints[w.constraint]; ok {
+		w.check = fn
+		return w, nil
+	}
+	reg, err := regexp.Compile("^(?:" + w.constraint + ")$")
+	if err != nil {
+		return w, errors.New("invalid path variable constraint {" + inner + "}: " + err.Error())
+	}
+	w.check = reg.MatchString
+	return w, nil
+}
+
+// parsePattern 与parsePath的切分规则一致，但不会切分{}中的内容，保证约束中的正则完整
+// 注意请求路径依然会按/和.切分，变量只能匹配其中的一段
+func parsePattern(pattern string) []string {
+	if !strings.Contains(pattern, "{") {
+		return parsePath(pattern)
+	}
+	pattern = filepath.Clean(pattern)
+	if pattern == "/" {
+		return []string{"/"}
+	}
+	var elements []string
+	depth, start := 0, 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth > 0 {
+				depth--
+			}
+		case '/', '.':
+			if depth > 0 {
+				continue
+			}
+			if i > start {
+				elements = append(elements, pattern[start:i])
+			}
+			start = i + 1
+		}
+	}
+	if start < len(pattern) {
+		elements = append(elements, pattern[start:])
+	}
+	return elements
+}
+
+// parseRoutePattern 切分路由的路径，段内通配符所在的整个/段不按.切分
+// 如 /img/img_*.png 切分为 img 和 img_*.png，*可以匹配含有.的部分，如 img_1.2.png
+// 单独的*依然是通配符，如 /static/*.html 中的*匹配任意多段
+func parseRoutePattern(pattern string) []string {
+	var elements []string
+	for _, seg := range strings.Split(filepath.Clean(pattern), "/") {
+		pieces := parsePattern("/" + seg)
+		if !strings.Contains(seg, "{") && len(pieces) > 1 {
+			for _, piece := range pieces {
+				if piece != "*" && strings.Contains(piece, "*") {
+					pieces = []string{strings.Trim(seg, ".")}
+					break
+				}
+			}
+		}
+		if len(pieces) == 1 && pieces[0] == "/" {
+			continue
+		}
+		elements = append(elements, pieces...)
+	}
+	if len(elements) == 0 {
+		return []string{"/"}
+	}
+	return elements
+}
+
+// segmentEnds 请求路径按parsePath切分后，每一段所在的/段结束的下标（不包含）
+// 用于段内通配符匹配整个/段
+func segmentEnds(url string) []int {
+	url = filepath.Clean(url)
+	if url == "" || url == "/" {
+		return []int{1}
+	}
+	var segs []int
+	seg := 0
+	for i := 0; i < len(url); i++ {
+		switch {
+		case url[i] == '/':
+			seg++
+		case url[i] == '.':
+		case i == 0 || url[i-1] == '/' || url[i-1] == '.':
+			segs = append(segs, seg)
+		}
+	}
+	ends := make([]int, len(segs))
+	for i := len(segs) - 1; i >= 0; i-- {
+		if i == len(segs)-1 || segs[i+1] != segs[i] {
+			ends[i] = i + 1
+		} else {
+			ends[i] = ends[i+1]
+		}
+	}
+	return ends
+}
+
+// globMatch 判断段s是否符合段内通配符，parts为按*切分后的各部分
+func globMatch(parts []string, s string) bool {
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+	last := len(parts) - 1
+	for _, part := range parts[1:last] {
+		idx := strings.Index(s, part)
+		if idx == -1 {
+			return false
+		}
+		s = s[idx+len(part):]
+	}
+	return strings.HasSuffix(s, parts[last])
+}
+
+// pathRemainder 返回请求路径从第k段开始剩余的部分，保留其中的分隔符
+// 段的切分规则和parsePath保持一致
+func pathRemainder(url string, k int) string {
+	url = filepath.Clean(url)
+	n := 0
+	for i := 0; i < len(url); i++ {
+		if url[i] == '/' || url[i] == '.' {
+			continue
+		}
+		if i == 0 || url[i-1] == '/' || url[i-1] == '.' {
+			if n == k {
+				return url[i:]
+			}
+			n++
+		}
+	}
+	return ""
+}
+
+// replaceVars 把路径中的每个{}变量替换为fn的返回值
+func replaceVars(path string, fn func(w word) (string, error)) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(path); i++ {
+		if path[i] != '{' {
+			sb.WriteByte(path[i])
+			continue
+		}
+		depth, j := 0, i
+		for ; j < len(path); j++ {
+			if path[j] == '{' {
+				depth++
+			} else if path[j] == '}' {
+				depth--
+				if depth == 0 {
+					break
+				}
+			}
+		}
+		if j == len(path) {
+			sb.WriteString(path[i:])
+			break
+		}
+		w, err := newVarWord(path[i+1 : j])
+		if err != nil {
+			return "", err
+		}
+		v, err := fn(w)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(v)
+		i = j
+	}
+	return sb.String(), nil
+}
+
+// ginPath 把cango的路径变量转换成gin的形式，/{name:int} -> /:name，/{path...} -> /*path
+func ginPath(path string) string {
+	if !strings.Contains(path, "{") {
+		return path
+	}
+	gp, _ := replaceVars(path, func(w word) (string, error) {
+		if w.isCatchAll {
+			return "*" + w.key, nil
+		}
+		return ":" + w.key, nil
+	})
+	return gp
+}
+
+// elementsToWords 把切分后的各段解析为word，变量的约束不合法或者{path...}不在最后时返回错误
+func elementsToWords(elements []string) ([]word, []int, bool, error) {
+	words := make([]word, len(elements))
+	idx := make([]int, len(elements))
+	isWildcard := false
+	j := 0
+	for i, elem := range elements {
+		if elem == "*" {
+			words[i] = word{isWildcard: true}
+			isWildcard = true
+			continue
+		}
+		// todo 如果这个word就是{}呢？
+		// todo 也就是说地址是/a/{}/b/c 这种的话不会被当做变量
+		// todo 如果真实需要注册的地址就是/a/{name}/b/c 应该怎么办？
+		if strings.HasPrefix(elem, "{") && strings.HasSuffix(elem, "}") {
+			w, err := newVarWord(elem[1 : len(elem)-1])
+			if err != nil {
+				return nil, nil, false, err
+			}
+			if w.isCatchAll && i != len(elements)-1 {
+				return nil, nil, false, errors.New("catch-all variable " + elem + " must be the last segment")
+			}
+			words[i] = w
+			idx[j] = i
+			j++
+			continue
+		}
+		if strings.Contains(elem, "*") {
+			words[i] = word{key: elem, glob: strings.Split(elem, "*")}
+			continue
+		}
+		words[i] = word{key: elem, isVar: false}
+	}
+	return words, idx[0:j], isWildcard, nil
+}
+
+func parsePath(url string) []string {
+	// todo : clean & split in only one loop
+	url = filepath.Clean(url)
+	// /a/b/c/ 在这里等价于 /a/b/c，是否允许这样访问由 PathPolicy 决定
+	if url == "" || url == "/" {
+		return []string{"/"}
+	}
+	// todo .这个分隔符应该是最后一个才需要
+	return strings.FieldsFunc(url, func(r rune) bool {
+		if r == '/' || r == '.' {
+			return true
+		}
+		return false
+	})
+}
@@ -0,0 +1,251 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parsePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "/", want: []string{"/"}},
+		{pattern: "/a/b.json", want: []string{"a", "b", "json"}},
+		{pattern: "/item/{id:int}", want: []string{"item", "{id:int}"}},
+		{pattern: `/v/{version:\d+\.\d+}.json`, want: []string{"v", `{version:\d+\.\d+}`, "json"}},
+		{pattern: "/slug/{slug:[a-z]{2,3}}/", want: []string{"slug", "{slug:[a-z]{2,3}}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := parsePattern(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePattern() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parseRoutePattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{pattern: "/", want: []string{"/"}},
+		{pattern: "/a/b.json", want: []string{"a", "b", "json"}},
+		{pattern: "/pics/img_*.png", want: []string{"pics", "img_*.png"}},
+		{pattern: "/static/*.html", want: []string{"static", "*", "html"}},
+		{pattern: `/v/{version:\d+\.\d+}.json`, want: []string{"v", `{version:\d+\.\d+}`, "json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if got := parseRoutePattern(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseRoutePattern() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ginPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/a/b", want: "/a/b"},
+		{path: "/a/{id}", want: "/a/:id"},
+		{path: "/a/{id:int}/b", want: "/a/:id/b"},
+		{path: "/a/{slug:[a-z]{2,3}}/b", want: "/a/:slug/b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := ginPath(tt.path); got != tt.want {
+				t.Errorf("ginPath() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_treeDispatcher_constraint(t *testing.T) {
+	td := newTreeDispatcher()
+	td.NewForwarder("any", nil).PathMethods("/item/{name}", http.MethodGet)
+	td.NewForwarder("int", nil).PathMethods("/item/{id:int}", http.MethodGet)
+	td.NewForwarder("slug", nil).PathMethods("/item/{slug:[a-z-]+}", http.MethodGet)
+	td.NewForwarder("uuid", nil).PathMethods("/obj/{uuid:uuid}", http.MethodGet)
+	tests := []struct {
+		url      string
+		wantName string
+		wantVars map[string]string
+	}{
+		{url: "/item/12", wantName: "int", wantVars: map[string]string{"id": "12"}},
+		{url: "/item/hello-world", wantName: "slug", wantVars: map[string]string{"slug": "hello-world"}},
+		{url: "/item/Hello_1", wantName: "any", wantVars: map[string]string{"name": "Hello_1"}},
+		{url: "/obj/123e4567-e89b-12d3-a456-426614174000", wantName: "uuid", wantVars: map[string]string{"uuid": "123e4567-e89b-12d3-a456-426614174000"}},
+		{url: "/obj/123", wantName: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			tm := td.doMatch(http.MethodGet, tt.url)
+			if tm == nil {
+				if tt.wantName != "" {
+					t.Errorf("doMatch() = nil, want %v", tt.wantName)
+				}
+				return
+			}
+			if tm.forwarder.name != tt.wantName || !reflect.DeepEqual(tm.vars, tt.wantVars) {
+				t.Errorf("doMatch() = %v %v, want %v %v", tm.forwarder.name, tm.vars, tt.wantName, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestCan_ServeHTTP_constraint(t *testing.T) {
+	can := NewCan().RouteFunc(func(ps struct {
+		URI `value:"/item/{id:int}"`
+		Id  int
+	}) interface{} {
+		return Content{String: "int"}
+	})
+	can.build()
+	tests := []struct {
+		url      string
+		wantCode int
+		wantBody string
+	}{
+		{url: "/item/12", wantCode: http.StatusOK, wantBody: "int"},
+		{url: "/item/abc", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func Test_globMatch(t *testing.T) {
+	tests := []struct {
+		glob string
+		s    string
+		want bool
+	}{
+		{glob: "img_*", s: "img_1", want: true},
+		{glob: "img_*", s: "img", want: false},
+		{glob: "*html", s: "indexhtml", want: true},
+		{glob: "a*b*c", s: "a1b2c", want: true},
+		{glob: "a*b*c", s: "a1c", want: false},
+		{glob: "ab*ba", s: "aba", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.glob+"|"+tt.s, func(t *testing.T) {
+			if got := globMatch(strings.Split(tt.glob, "*"), tt.s); got != tt.want {
+				t.Errorf("globMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_pathRemainder(t *testing.T) {
+	tests := []struct {
+		url  string
+		k    int
+		want string
+	}{
+		{url: "/", k: 0, want: ""},
+		{url: "/files", k: 1, want: ""},
+		{url: "/files/a/b.txt", k: 1, want: "a/b.txt"},
+		{url: "/files//a/./b.txt/", k: 1, want: "a/b.txt"},
+		{url: "/files/a/b.txt", k: 3, want: "txt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := pathRemainder(tt.url, tt.k); got != tt.want {
+				t.Errorf("pathRemainder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_segmentEnds(t *testing.T) {
+	tests := []struct {
+		url  string
+		want []int
+	}{
+		{url: "/", want: []int{1}},
+		{url: "/a/b.json", want: []int{1, 3, 3}},
+		{url: "/pics/img_1.2.png/x", want: []int{1, 4, 4, 4, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := segmentEnds(tt.url); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("segmentEnds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_treeDispatcher_catchAll(t *testing.T) {
+	td := newTreeDispatcher()
+	td.NewForwarder("files", nil).PathMethods("/files/{path...}", http.MethodGet)
+	td.NewForwarder("proxy", nil).PathMethods("/proxy/{host}/{rest...}", http.MethodGet)
+	td.NewForwarder("img", nil).PathMethods("/files/img_*", http.MethodGet)
+	td.NewForwarder("log", nil).PathMethods("/log/*html", http.MethodGet)
+	td.NewForwarder("png", nil).PathMethods("/pics/img_*.png", http.MethodGet)
+	td.NewForwarder("html", nil).PathMethods("/static/*.html", http.MethodGet)
+	tests := []struct {
+		url      string
+		wantName string
+		wantVars map[string]string
+	}{
+		{url: "/files", wantName: "files", wantVars: map[string]string{"path": ""}},
+		{url: "/files/a/b/c.txt", wantName: "files", wantVars: map[string]string{"path": "a/b/c.txt"}},
+		{url: "/files/img_1", wantName: "img", wantVars: map[string]string{}},
+		{url: "/files/img", wantName: "files", wantVars: map[string]string{"path": "img"}},
+		{url: "/proxy", wantName: ""},
+		{url: "/proxy/localhost", wantName: "proxy", wantVars: map[string]string{"host": "localhost", "rest": ""}},
+		{url: "/proxy/host/api/v1", wantName: "proxy", wantVars: map[string]string{"host": "host", "rest": "api/v1"}},
+		{url: "/log/indexhtml", wantName: "log", wantVars: map[string]string{}},
+		{url: "/log/index", wantName: ""},
+		{url: "/pics/img_1.png", wantName: "png", wantVars: map[string]string{}},
+		{url: "/pics/img_1.2.png", wantName: "png", wantVars: map[string]string{}},
+		{url: "/pics/img_1.jpg", wantName: ""},
+		{url: "/pics/a/img_1.png", wantName: ""},
+		{url: "/static/a/b.html", wantName: "html", wantVars: map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			tm := td.doMatch(http.MethodGet, tt.url)
+			if tm == nil {
+				if tt.wantName != "" {
+					t.Errorf("doMatch() = nil, want %v", tt.wantName)
+				}
+				return
+			}
+			if tm.forwarder.name != tt.wantName || !reflect.DeepEqual(tm.vars, tt.wantVars) {
+				t.Errorf("doMatch() = %v %v, want %v %v", tm.forwarder.name, tm.vars, tt.wantName, tt.wantVars)
+			}
+		})
+	}
+}
+
+func TestCan_ServeHTTP_catchAll(t *testing.T) {
+	can := NewCan().RouteFunc(func(ps struct {
+		URI  `value:"/files/{path...}"`
+		Path string
+	}) interface{} {
+		return Content{String: ps.Path}
+	})
+	can.build()
+	rec := httptest.NewRecorder()
+	can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/files/docs/readme.md", nil))
+	if rec.Body.String() != "docs/readme.md" {
+		t.Errorf("ServeHTTP() body = %v, want docs/readme.md", rec.Body.String())
+	}
+}
@@ -14,14 +14,100 @@
 package cango
 
 import (
+	"errors"
 	"net/http"
+
+	"github.com/JessonChan/canlog"
 )
 
+// errorHandleMap 框架默认的错误处理返回problem+json，见 Problem
 var errorHandleMap = map[int]func(w http.ResponseWriter, r *http.Request){
-	http.StatusNotFound: http.NotFound,
+	http.StatusBadRequest: problemHandler(http.StatusBadRequest),
+	http.StatusNotFound:   problemHandler(http.StatusNotFound),
+	// Allow 头在路由匹配时已经设置
+	http.StatusMethodNotAllowed:    problemHandler(http.StatusMethodNotAllowed),
+	http.StatusInternalServerError: problemHandler(http.StatusInternalServerError),
 }
 
+// customErrorHandle 通过SetError注册的状态码，处理函数返回的错误只在这时使用注册的页面
+var customErrorHandle = map[int]bool{}
+
 // SetError can define http status code with specific method
 func SetError(code int, fn func(w http.ResponseWriter, r *http.Request)) {
 	errorHandleMap[code] = fn
+	customErrorHandle[code] = true
+}
+
+// HTTPError 带有状态码的错误，处理函数返回这种错误时使用它的状态码
+type HTTPError interface {
+	error
+	StatusCode() int
+}
+
+type httpError struct {
+	code int
+	msg  string
+}
+
+func (e *httpError) Error() string   { return e.msg }
+func (e *httpError) StatusCode() int { return e.code }
+
+// NewHTTPError 生成一个带有状态码的错误
+func NewHTTPError(code int, msg string) HTTPError {
+	return &httpError{code: code, msg: msg}
+}
+
+// errorReturn 处理函数 (T, error) 中返回的不为nil的error
+type errorReturn struct {
+	err error
+}
+
+// SetErrorMapper 设置处理函数返回的error到状态码的映射，返回0时使用默认的500
+// 实现了HTTPError的错误优先使用自己的状态码
+func (can *Can) SetErrorMapper(fn func(err error) int) *Can {
+	can.errorMapper = fn
+	return can
+}
+
+func (can *Can) errorStatus(err error) int {
+	var he HTTPError
+	if errors.As(err, &he) && he.StatusCode() != 0 {
+		return he.StatusCode()
+	}
+	if can.errorMapper != nil {
+		if code := can.errorMapper(err); code != 0 {
+			return code
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// renderError 请求页面时使用SetError注册的错误页面，没有注册时返回错误信息的文本，否则返回problem+json
+// 5xx的错误只记录日志，不把内部的错误信息返回给客户端，除非返回的就是Problem
+func (can *Can) renderError(w http.ResponseWriter, r *http.Request, err error) {
+	code := can.errorStatus(err)
+	var p Problem
+	if !errors.As(err, &p) {
+		p = NewProblem(code, err.Error())
+		if code >= http.StatusInternalServerError {
+			p.Detail = ""
+		}
+	}
+	p.Status = code
+	if code >= http.StatusInternalServerError {
+		canlog.CanError(r.Method, r.URL.Path, err)
+	}
+	if !acceptHTML(r) {
+		writeProblem(w, r, p)
+		return
+	}
+	if customErrorHandle[code] {
+		errorHandleMap[code](w, r)
+		return
+	}
+	msg := p.Detail
+	if msg == "" {
+		msg = http.StatusText(code)
+	}
+	http.Error(w, msg, code)
 }
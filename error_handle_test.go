@@ -0,0 +1,251 @@
+package cango
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type allowCtrl struct {
+	URI `value:"/allow"`
+}
+
+func (c *allowCtrl) Get(ps struct {
+	URI `value:"/me"`
+}) interface{} {
+	return Content{String: "me"}
+}
+
+func (c *allowCtrl) Post(ps struct {
+	URI `value:"/{id}"`
+	PostMethod
+}) interface{} {
+	return Content{String: "post"}
+}
+
+func (c *allowCtrl) Delete(ps struct {
+	URI `value:"/{id}"`
+	DeleteMethod
+}) interface{} {
+	return Content{String: "delete"}
+}
+
+func TestCan_ServeHTTP_methodNotAllowed(t *testing.T) {
+	can := NewCan().Route(&allowCtrl{})
+	can.build()
+	tests := []struct {
+		method    string
+		url       string
+		wantCode  int
+		wantAllow string
+	}{
+		{method: http.MethodGet, url: "/allow/me", wantCode: http.StatusOK},
+		{method: http.MethodPost, url: "/allow/me", wantCode: http.StatusOK},
+		{method: http.MethodPut, url: "/allow/me", wantCode: http.StatusMethodNotAllowed, wantAllow: "DELETE, GET, HEAD, OPTIONS, POST"},
+		{method: http.MethodGet, url: "/allow/12", wantCode: http.StatusMethodNotAllowed, wantAllow: "DELETE, OPTIONS, POST"},
+		{method: http.MethodGet, url: "/allow//12", wantCode: http.StatusMethodNotAllowed, wantAllow: "DELETE, OPTIONS, POST"},
+		{method: http.MethodGet, url: "/none", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("ServeHTTP() Allow = %v, want %v", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+func TestSetError_methodNotAllowed(t *testing.T) {
+	origin := errorHandleMap[http.StatusMethodNotAllowed]
+	defer func() {
+		errorHandleMap[http.StatusMethodNotAllowed] = origin
+		delete(customErrorHandle, http.StatusMethodNotAllowed)
+	}()
+	SetError(http.StatusMethodNotAllowed, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = w.Write([]byte("custom"))
+	})
+	can := NewCan().Route(&allowCtrl{})
+	can.build()
+	rec := httptest.NewRecorder()
+	can.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/allow/12", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Body.String() != "custom" || rec.Header().Get("Allow") == "" {
+		t.Errorf("ServeHTTP() = %v %v %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+}
+
+type optionsCtrl struct {
+	URI `value:"/options"`
+}
+
+func (c *optionsCtrl) Get(ps struct {
+	URI `value:"/get"`
+}) interface{} {
+	ps.Request().ResponseWriter.Header().Set("X-Get", "1")
+	return Content{String: "body"}
+}
+
+func (c *optionsCtrl) Head(ps struct {
+	URI `value:"/head"`
+	GetMethod
+	HeadMethod
+}) interface{} {
+	ps.Request().ResponseWriter.Header().Set("X-Head", "1")
+	return Content{String: "body"}
+}
+
+func (c *optionsCtrl) Options(ps struct {
+	URI `value:"/head"`
+	OptionsMethod
+}) interface{} {
+	return Content{String: "explicit"}
+}
+
+func TestCan_ServeHTTP_headAndOptions(t *testing.T) {
+	can := NewCan().Route(&optionsCtrl{})
+	can.build()
+	tests := []struct {
+		method     string
+		url        string
+		wantCode   int
+		wantHeader string
+		wantBody   string
+		wantAllow  string
+	}{
+		{method: http.MethodHead, url: "/options/get", wantCode: http.StatusOK, wantHeader: "X-Get"},
+		{method: http.MethodHead, url: "/options/head", wantCode: http.StatusOK, wantHeader: "X-Head"},
+		{method: http.MethodOptions, url: "/options/get", wantCode: http.StatusNoContent, wantAllow: "GET, HEAD, OPTIONS"},
+		{method: http.MethodOptions, url: "/options/head", wantCode: http.StatusOK, wantBody: "explicit"},
+		{method: http.MethodOptions, url: "/options/none", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantHeader != "" && rec.Header().Get(tt.wantHeader) == "" {
+				t.Errorf("ServeHTTP() header %v missing", tt.wantHeader)
+			}
+			if tt.wantCode != http.StatusNotFound && rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("ServeHTTP() Allow = %v, want %v", got, tt.wantAllow)
+			}
+		})
+	}
+}
+
+var errNoUser = errors.New("no such user")
+
+type errorCtrl struct {
+	URI `value:"/err"`
+}
+
+func (c *errorCtrl) User(ps struct {
+	URI `value:"/user/{id}"`
+	Id  string
+}) (map[string]string, error) {
+	switch ps.Id {
+	case "1":
+		return map[string]string{"id": ps.Id}, nil
+	case "400":
+		return nil, NewHTTPError(http.StatusBadRequest, "bad id")
+	case "404":
+		return nil, fmt.Errorf("find user %s: %w", ps.Id, errNoUser)
+	}
+	return nil, errors.New("db is down")
+}
+
+func (c *errorCtrl) Delete(ps struct {
+	URI `value:"/delete"`
+}) error {
+	return nil
+}
+
+func TestCan_ServeHTTP_errorReturn(t *testing.T) {
+	can := NewCan().Route(&errorCtrl{}).SetErrorMapper(func(err error) int {
+		if errors.Is(err, errNoUser) {
+			return http.StatusNotFound
+		}
+		return 0
+	})
+	can.build()
+	tests := []struct {
+		url      string
+		accept   string
+		wantCode int
+		wantBody string
+	}{
+		{url: "/err/user/1", wantCode: http.StatusOK, wantBody: `{"id":"1"}`},
+		{url: "/err/user/400", accept: "application/json", wantCode: http.StatusBadRequest, wantBody: `{"detail":"bad id","instance":"/err/user/400","status":400,"title":"Bad Request"}`},
+		{url: "/err/user/400", accept: "text/html", wantCode: http.StatusBadRequest, wantBody: "bad id\n"},
+		{url: "/err/user/404", wantCode: http.StatusNotFound, wantBody: `{"detail":"find user 404: no such user","instance":"/err/user/404","status":404,"title":"Not Found"}`},
+		{url: "/err/user/404", accept: "text/html", wantCode: http.StatusNotFound, wantBody: "find user 404: no such user\n"},
+		{url: "/err/user/500", accept: "text/html", wantCode: http.StatusInternalServerError, wantBody: "Internal Server Error\n"},
+		{url: "/err/user/500", accept: "application/json", wantCode: http.StatusInternalServerError, wantBody: `{"instance":"/err/user/500","status":500,"title":"Internal Server Error"}`},
+		{url: "/err/delete", wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url+tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCan_ToGins_errorReturn(t *testing.T) {
+	can := NewCan().Route(&errorCtrl{}).SetErrorMapper(func(err error) int {
+		if errors.Is(err, errNoUser) {
+			return http.StatusNotFound
+		}
+		return 0
+	})
+	var handle func(ctx *gin.Context)
+	for _, gh := range can.ToGins() {
+		if gh.Url == "/err/user/:id" {
+			handle = gh.Handle
+		}
+	}
+	if handle == nil {
+		t.Fatal("ToGins() missing /err/user/:id")
+	}
+	tests := []struct {
+		url      string
+		wantCode int
+	}{
+		{url: "/err/user/400", wantCode: http.StatusBadRequest},
+		{url: "/err/user/404", wantCode: http.StatusNotFound},
+		{url: "/err/user/500", wantCode: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(rec)
+			ctx.Request = httptest.NewRequest(http.MethodGet, tt.url, nil)
+			handle(ctx)
+			if rec.Code != tt.wantCode {
+				t.Errorf("Handle() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+		})
+	}
+}
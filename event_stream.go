@@ -0,0 +1,193 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JessonChan/canlog"
+)
+
+const mimeEventStream = "text/event-stream"
+
+// defaultHeartbeat EventStream默认的心跳间隔
+const defaultHeartbeat = 15 * time.Second
+
+// Event 一条Server-Sent Events事件
+type Event struct {
+	Id    string
+	Event string
+	// Data 为string或者[]byte时原样输出，其它类型输出JSON，多行时每行一个data字段
+	Data interface{}
+	// Retry 客户端断线重连的间隔，为0时不输出
+	Retry time.Duration
+}
+
+// EventStream 以text/event-stream的形式持续返回事件，客户端断开时结束
+//
+//	return cango.EventStream{Events: ch}
+//	return cango.EventStream{Stream: func(ctx context.Context, send func(cango.Event) error) error {...}}
+//
+// Events 和 Stream 二选一，Events关闭或者Stream返回时结束
+type EventStream struct {
+	Events <-chan Event
+	// Stream 在请求的goroutine中执行，send返回error时表示客户端已经断开，ctx在客户端断开时取消
+	Stream func(ctx context.Context, send func(Event) error) error
+	// Heartbeat 发送心跳注释的间隔，为0时使用15s，小于0时不发送
+	Heartbeat time.Duration
+}
+
+var eventFieldReplacer = strings.NewReplacer("\r", "", "\n", "")
+
+// eventWriter 事件和心跳可能在不同的goroutine中写入，需要加锁
+type eventWriter struct {
+	mu  sync.Mutex
+	ctx context.Context
+	w   io.Writer
+	f   http.Flusher
+}
+
+func (ew *eventWriter) send(ev Event) error {
+	var sb strings.Builder
+	if ev.Id != "" {
+		sb.WriteString("id: " + eventFieldReplacer.Replace(ev.Id) + "\n")
+	}
+	if ev.Event != "" {
+		sb.WriteString("event: " + eventFieldReplacer.Replace(ev.Event) + "\n")
+	}
+	if ev.Retry > 0 {
+		sb.WriteString("retry: " + strconv.FormatInt(ev.Retry.Milliseconds(), 10) + "\n")
+	}
+	var data string
+	switch d := ev.Data.(type) {
+	case nil:
+	case string:
+		data = d
+	case []byte:
+		data = string(d)
+	default:
+		bs, err := responseJsonHandler(d)
+		if err != nil {
+			return err
+		}
+		data = string(bs)
+	}
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		sb.WriteString("data: " + line + "\n")
+	}
+	sb.WriteString("\n")
+	return ew.write(sb.String())
+}
+
+func (ew *eventWriter) comment(s string) error {
+	return ew.write(": " + s + "\n\n")
+}
+
+func (ew *eventWriter) write(s string) error {
+	ew.mu.Lock()
+	defer ew.mu.Unlock()
+	if err := ew.ctx.Err(); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(ew.w, s); err != nil {
+		return err
+	}
+	ew.f.Flush()
+	return nil
+}
+
+// serveEvents 设置事件流的header，逐条写入并flush事件，定时发送心跳
+func serveEvents(w http.ResponseWriter, r *http.Request, es EventStream) {
+	h := w.Header()
+	h.Set("Content-Type", mimeEventStream)
+	h.Set("Cache-Control", "no-cache")
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	f, ok := w.(http.Flusher)
+	if !ok {
+		canlog.CanError(r.Method, r.URL.Path, "event stream: ResponseWriter is not a http.Flusher")
+		h.Del("Content-Type")
+		writeProblem(w, r, NewProblem(http.StatusInternalServerError, ""))
+		return
+	}
+	h.Set("Connection", "keep-alive")
+	// 关闭nginx的缓冲
+	h.Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	f.Flush()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	ew := &eventWriter{ctx: ctx, w: w, f: f}
+
+	heartbeat := es.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = defaultHeartbeat
+	}
+	if heartbeat > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ticker := time.NewTicker(heartbeat)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if ew.comment("heartbeat") != nil {
+						cancel()
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	if es.Stream != nil {
+		if err := es.Stream(ctx, ew.send); err != nil && ctx.Err() == nil {
+			canlog.CanError(r.Method, r.URL.Path, err)
+		}
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev, ok := <-es.Events:
+			if !ok {
+				return
+			}
+			if err := ew.send(ev); err != nil {
+				if ctx.Err() == nil {
+					canlog.CanError(r.Method, r.URL.Path, err)
+				}
+				return
+			}
+		}
+	}
+}
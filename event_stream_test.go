@@ -0,0 +1,97 @@
+package cango
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type eventCtrl struct {
+	URI `value:"/events"`
+	// done 记录Stream结束时context的错误
+	done chan error
+}
+
+func (c *eventCtrl) List(ps struct {
+	URI `value:"/list"`
+}) interface{} {
+	ch := make(chan Event, 3)
+	ch <- Event{Id: "1", Event: "user", Data: map[string]int{"id": 1}, Retry: time.Second}
+	ch <- Event{Data: "line1\nline2"}
+	ch <- Event{Id: "3\n", Data: []byte("bytes")}
+	close(ch)
+	return EventStream{Events: ch, Heartbeat: -1}
+}
+
+func (c *eventCtrl) Live(ps struct {
+	URI `value:"/live"`
+}) interface{} {
+	return &EventStream{Heartbeat: 10 * time.Millisecond, Stream: func(ctx context.Context, send func(Event) error) error {
+		if err := send(Event{Data: "hello"}); err != nil {
+			return err
+		}
+		<-ctx.Done()
+		c.done <- ctx.Err()
+		return ctx.Err()
+	}}
+}
+
+func TestCan_ServeHTTP_eventStream(t *testing.T) {
+	can := NewCan().Route(&eventCtrl{})
+	can.build()
+	rec := httptest.NewRecorder()
+	can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events/list", nil))
+	want := "id: 1\nevent: user\nretry: 1000\ndata: {\"id\":1}\n\n" +
+		"data: line1\ndata: line2\n\n" +
+		"id: 3\ndata: bytes\n\n"
+	if rec.Code != http.StatusOK || rec.Body.String() != want || !rec.Flushed {
+		t.Errorf("ServeHTTP() = %v %q, want %q", rec.Code, rec.Body.String(), want)
+	}
+	if got := rec.Header().Get("Content-Type"); got != mimeEventStream {
+		t.Errorf("ServeHTTP() Content-Type = %v", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-cache" {
+		t.Errorf("ServeHTTP() Cache-Control = %v", got)
+	}
+}
+
+func TestCan_ServeHTTP_eventStreamDisconnect(t *testing.T) {
+	ctrl := &eventCtrl{done: make(chan error, 1)}
+	can := NewCan().Route(ctrl)
+	can.build()
+	srv := httptest.NewServer(can)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events/live", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 4 {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	if lines[0] != "data: hello\n" || lines[1] != "\n" || lines[2] != ": heartbeat\n" {
+		t.Errorf("event stream = %q", lines)
+	}
+	cancel()
+	_ = resp.Body.Close()
+	select {
+	case err := <-ctrl.done:
+		if !strings.Contains(err.Error(), "canceled") {
+			t.Errorf("Stream ctx.Err() = %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stream is not canceled after the client disconnects")
+	}
+}
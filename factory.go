@@ -16,6 +16,8 @@ package cango
 import (
 	"reflect"
 	"strings"
+	"sync"
+	"time"
 )
 
 type (
@@ -26,6 +28,14 @@ type (
 	handlerMethod struct {
 		fn       reflect.Method
 		patterns []*handlePath
+		// param 带有URI的参数类型
+		param reflect.Type
+		// host 参数中URI字段的host标签
+		host string
+		// plan 参数绑定计划，和方法一起缓存
+		plan *bindPlan
+		// timeout 参数中URI字段的timeout标签
+		timeout time.Duration
 	}
 
 	handlePath struct {
@@ -34,8 +44,9 @@ type (
 	}
 )
 
-var cacheStruct = map[reflect.Type]*handlerStruct{}
-var cacheMethod = map[reflect.Method]*handlerMethod{}
+// 解析结果的缓存，多个Can可能同时在构建路由，使用sync.Map
+var cacheStruct sync.Map // map[reflect.Type]*handlerStruct
+var cacheMethod sync.Map // map[reflect.Method]*handlerMethod
 
 func factory(i interface{}) *handlerStruct {
 	return factoryType(toPtrKind(i))
@@ -60,12 +71,10 @@ func implements(in, typ reflect.Type) reflect.Type {
 }
 
 func factoryType(typ reflect.Type) *handlerStruct {
-	hs, ok := cacheStruct[typ]
-	if ok {
-		return hs
+	if hs, ok := cacheStruct.Load(typ); ok {
+		return hs.(*handlerStruct)
 	}
-	hs = &handlerStruct{typ: typ}
-	cacheStruct[typ] = hs
+	hs := &handlerStruct{typ: typ}
 	for i := 0; i < typ.NumMethod(); i++ {
 		m := typ.Method(i)
 		if m.PkgPath != "" {
@@ -77,18 +86,19 @@ func factoryType(typ reflect.Type) *handlerStruct {
 		}
 
 	}
-	return hs
+	actual, _ := cacheStruct.LoadOrStore(typ, hs)
+	return actual.(*handlerStruct)
 }
 
 func factoryMethod(m reflect.Method, invokeByWho int) *handlerMethod {
-	if hm, ok := cacheMethod[m]; ok {
-		return hm
+	if hm, ok := cacheMethod.Load(m); ok {
+		return hm.(*handlerMethod)
 	}
 	if uriInterfaceContains(m) {
 		return nil
 	}
 	// 只接受参数列表最后一个做为uri,如果在参数中不包含uri，则放弃该路由
-	// todo 支持根据路由名和方法名进行路由定义？？？
+	// 根据结构体名和方法名定义路由见 RouteConvention
 	for j := m.Type.NumIn(); j > invokeByWho; j-- {
 		in := implements(m.Type.In(j-1), uriType)
 		if in == nil {
@@ -99,7 +109,9 @@ func factoryMethod(m reflect.Method, invokeByWho int) *handlerMethod {
 		// func (c *Controller)Ping(cango.URI)interface{}
 		case reflect.Interface:
 			hm := &handlerMethod{
-				fn: m,
+				fn:    m,
+				param: in,
+				plan:  bindPlanOf(m.Type),
 				patterns: func() (pms []*handlePath) {
 					return []*handlePath{{
 						path:        "",
@@ -107,8 +119,8 @@ func factoryMethod(m reflect.Method, invokeByWho int) *handlerMethod {
 					}}
 				}(),
 			}
-			cacheMethod[m] = hm
-			return hm
+			actual, _ := cacheMethod.LoadOrStore(m, hm)
+			return actual.(*handlerMethod)
 		case reflect.Struct:
 			uriFiled, ok := in.FieldByName(uriName)
 			if !ok {
@@ -123,7 +135,7 @@ func factoryMethod(m reflect.Method, invokeByWho int) *handlerMethod {
 				}
 				return
 			}()
-			hm := &handlerMethod{fn: m}
+			hm := &handlerMethod{fn: m, param: in, host: uriFiled.Tag.Get(hostTagName), plan: bindPlanOf(m.Type), timeout: parseTimeout(uriFiled.Tag)}
 			// 没有在方法定义路径，需要用空的字段把方法带出去
 			if len(paths) == 0 {
 				paths = []string{""}
@@ -135,8 +147,8 @@ func factoryMethod(m reflect.Method, invokeByWho int) *handlerMethod {
 					httpMethods: methods,
 				})
 			}
-			cacheMethod[m] = hm
-			return hm
+			actual, _ := cacheMethod.LoadOrStore(m, hm)
+			return actual.(*handlerMethod)
 		}
 	}
 	return nil
@@ -157,6 +169,17 @@ func urlStr(typ reflect.Type) ([]string, string) {
 	return []string{}, ""
 }
 
+// tagHost get host from the tag of uri field
+func tagHost(typ reflect.Type) string {
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath == "" && f.Type == uriType {
+			return f.Tag.Get(hostTagName)
+		}
+	}
+	return ""
+}
+
 func tagUriParse(tag reflect.StructTag) []string {
 	if s := tag.Get(uriTagName); s != "" {
 		return strings.Split(s, ";")
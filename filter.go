@@ -17,6 +17,7 @@ package cango
 import (
 	"path/filepath"
 	"reflect"
+	"sort"
 )
 
 // todo 为什么filter 不使用和URI一样的方式进行注册
@@ -67,6 +68,10 @@ var filterImpl = reflect.ValueOf(&emptyFilter{})
 var filterType = reflect.TypeOf((*Filter)(nil)).Elem()
 var filterName = filterType.Name()
 
+func filterTypeName(f Filter) string {
+	return reflect.TypeOf(f).Elem().Name()
+}
+
 var filterRegMap = map[Filter][]string{}
 
 func RegisterFilter(filter Filter, values ...string) bool {
@@ -74,31 +79,57 @@ func RegisterFilter(filter Filter, values ...string) bool {
 	return true
 }
 
-func (can *Can) buildFilter() {
+// buildFilter 为路由表构建filter，注册的filterDispatcher只保存注册信息，不会被修改
+func (can *Can) buildFilter(t *routeTable) {
 	for filter := range filterRegMap {
-		can.Filter(filter)
+		if !can.removedFilters[reflect.TypeOf(filter)] {
+			can.addFilter(filter)
+		}
 	}
 
-	for flt, fd := range can.filterDispatcher {
+	for flt, registered := range can.filterDispatcher {
+		if can.removedFilters[flt] {
+			continue
+		}
+		fd := &filterDispatcher{filter: registered.filter, dispatcher: newCanMux()}
+		t.filters = append(t.filters, fd)
 		paths, methods := getPaths(flt)
 		for _, path := range paths {
 			buildSingleFilter(fd.dispatcher, fd.filter, filepath.Clean(path), methods)
 		}
-		for _, typ := range fd.uriTypes {
-			hs := factoryType(typ)
-			urls, _ := urlStr(typ.Elem())
-			if len(urls) == 0 {
-				urls = []string{""}
-			}
-			for _, fn := range hs.fns {
-				for _, pattern := range fn.patterns {
-					for _, url := range urls {
-						buildSingleFilter(fd.dispatcher, fd.filter, filepath.Clean(url+"/"+pattern.path), pattern.httpMethods)
-					}
+		// 结构体上的filter使用构建好的路由，按约定生成的和在分组中注册的路由都能生效
+		for _, typ := range registered.uriTypes {
+			for _, rr := range t.routes {
+				if rr.invoker != nil && rr.invoker.kind == invokeByReceiver && rr.invoker.Type.In(0) == typ {
+					buildSingleFilter(fd.dispatcher.(*canDispatcher).host(rr.host), fd.filter, rr.path, rr.methods)
 				}
 			}
 		}
 	}
+	buildGroupFilter(t, can.removedFilters)
+}
+
+// buildGroupFilter 分组的filter只注册组内路由的路径，同一个filter对象共用一个dispatcher
+// 执行顺序为先全局的filter（按名字排序），再分组的filter（外层分组先执行），被移除的filter不再使用
+func buildGroupFilter(t *routeTable, removed map[FilterType]bool) {
+	sort.Slice(t.filters, func(i, j int) bool {
+		return filterTypeName(t.filters[i].filter) < filterTypeName(t.filters[j].filter)
+	})
+	groupFds := map[Filter]*filterDispatcher{}
+	for _, rr := range t.routes {
+		for _, f := range rr.group.chainFilters() {
+			if removed[reflect.TypeOf(f)] {
+				continue
+			}
+			fd := groupFds[f]
+			if fd == nil {
+				fd = newFilterDispatcher(f)
+				groupFds[f] = fd
+				t.filters = append(t.filters, fd)
+			}
+			buildSingleFilter(fd.dispatcher.(*canDispatcher).host(rr.host), f, rr.path, rr.methods)
+		}
+	}
 }
 
 func getPaths(typ reflect.Type) ([]string, []string) {
@@ -126,12 +157,10 @@ func buildSingleFilter(dsp dispatcher, f Filter, path string, methods []string)
 	if len(methods) == 0 {
 		methods = defaultHTTPMethods
 	}
-	fv := reflect.ValueOf(f)
-	if fv.Kind() != reflect.Ptr {
+	if reflect.ValueOf(f).Kind() != reflect.Ptr {
 		panic("filter must be ptr")
 	}
-	name := fv.Elem().Type().Name()
-	dsp.NewForwarder(name, &Invoker{kind: invokeByFilter, filter: f}).PathMethods(path, methods...)
+	dsp.NewForwarder(filterTypeName(f), &Invoker{kind: invokeByFilter, filter: f}).PathMethods(path, methods...)
 }
 
 func (can *Can) filter(f Filter, uri URI) {
@@ -143,6 +172,7 @@ func (can *Can) filter(f Filter, uri URI) {
 	fd := can.filterDispatcher[typeOf]
 	if fd == nil {
 		fd = newFilterDispatcher(f)
+		can.filterDispatcher[typeOf] = fd
 	}
 	contain := false
 	for _, t := range fd.uriTypes {
@@ -157,7 +187,17 @@ func (can *Can) filter(f Filter, uri URI) {
 	fd.uriTypes = append(fd.uriTypes, rp.Type())
 }
 
+// Filter 注册filter，uris为filter生效的结构体，为空时使用filter的Value字段
+// 在运行中调用时会立即生效
 func (can *Can) Filter(f Filter, uris ...URI) *Can {
+	can.register(func() {
+		delete(can.removedFilters, reflect.TypeOf(f))
+		can.addFilter(f, uris...)
+	})
+	return can
+}
+
+func (can *Can) addFilter(f Filter, uris ...URI) {
 	rp := reflect.ValueOf(f)
 	if rp.Kind() == reflect.Ptr {
 		rp = rp.Elem()
@@ -185,5 +225,4 @@ func (can *Can) Filter(f Filter, uris ...URI) *Can {
 			can.filter(f, uri)
 		}
 	}
-	return can
 }
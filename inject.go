@@ -0,0 +1,131 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// service 通过Service注册的依赖
+type service struct {
+	name  string
+	value reflect.Value
+}
+
+// Service 注册控制器的依赖，控制器中带有inject标签的字段在构建路由时注入
+// inject:"" 按照类型查找，类型相同的优先，其次是可以赋值的（如接口），找到多个时需要使用名字
+// inject:"name" 按照Service注册时的名字查找
+// 在运行中调用时会立即生效，svc不能为nil
+func (can *Can) Service(svc interface{}, name ...string) *Can {
+	sv := reflect.ValueOf(svc)
+	if !sv.IsValid() || (sv.Kind() == reflect.Ptr && sv.IsNil()) {
+		panic("service must not be nil")
+	}
+	can.register(func() {
+		can.services = append(can.services, service{name: append(name, "")[0], value: sv})
+	})
+	return can
+}
+
+// resolve 查找可以赋值给typ的依赖
+func (can *Can) resolve(typ reflect.Type, name string) (reflect.Value, error) {
+	if name != "" {
+		for _, svc := range can.services {
+			if svc.name != name {
+				continue
+			}
+			if !svc.value.Type().AssignableTo(typ) {
+				return reflect.Value{}, fmt.Errorf("service %s is %s, not assignable to %s", name, svc.value.Type(), typ)
+			}
+			return svc.value, nil
+		}
+		return reflect.Value{}, fmt.Errorf("no service named %s", name)
+	}
+	var same, found []reflect.Value
+	for _, svc := range can.services {
+		if svc.value.Type() == typ {
+			same = append(same, svc.value)
+		} else if svc.value.Type().AssignableTo(typ) {
+			found = append(found, svc.value)
+		}
+	}
+	if len(same) > 0 {
+		found = same
+	}
+	switch len(found) {
+	case 0:
+		return reflect.Value{}, fmt.Errorf("no service of type %s", typ)
+	case 1:
+		return found[0], nil
+	}
+	return reflect.Value{}, fmt.Errorf("%d services assignable to %s, use inject:\"name\"", len(found), typ)
+}
+
+// newReceiver 生成控制器的原型，请求时复制原型做为接收者
+// 原型保留了Route时传入的实例上的字段，并注入了带有inject标签的字段
+func (can *Can) newReceiver(t *routeTable, ctrl interface{}, typ reflect.Type) reflect.Value {
+	proto := reflect.New(typ.Elem())
+	if cv := reflect.ValueOf(ctrl); cv.IsValid() && !(cv.Kind() == reflect.Ptr && cv.IsNil()) {
+		proto.Elem().Set(value(cv))
+	}
+	elem := typ.Elem()
+	for i := 0; i < elem.NumField(); i++ {
+		sf := elem.Field(i)
+		name, ok := sf.Tag.Lookup(injectTagName)
+		if !ok {
+			continue
+		}
+		if sf.PkgPath != "" {
+			t.injectErrors = append(t.injectErrors, fmt.Sprintf("%s.%s: unexported field can't be injected", elem, sf.Name))
+			continue
+		}
+		v, err := can.resolve(sf.Type, name)
+		if err != nil {
+			t.injectErrors = append(t.injectErrors, fmt.Sprintf("%s.%s: %s", elem, sf.Name, err))
+			continue
+		}
+		proto.Elem().Field(i).Set(v)
+	}
+	return proto
+}
+
+// bindableFields 返回原型上可以绑定请求中的值的字段
+// 带有inject标签的字段和Route时传入的实例上已经设置的字段保持原型的值，不被请求修改
+func bindableFields(proto reflect.Value) []int {
+	elem := proto.Elem()
+	var fields []int
+	for i := 0; i < elem.NumField(); i++ {
+		sf := elem.Type().Field(i)
+		if sf.PkgPath != "" || sf.Name == uriName || sf.Name == constructorTypeName {
+			continue
+		}
+		if _, ok := sf.Tag.Lookup(injectTagName); ok || !elem.Field(i).IsZero() {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
+
+// checkInject 返回构建路由时无法注入的依赖
+func checkInject(t *routeTable) error {
+	if len(t.injectErrors) > 0 {
+		return errors.New("cango inject:\n\t" + strings.Join(t.injectErrors, "\n\t"))
+	}
+	return nil
+}
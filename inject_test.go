@@ -0,0 +1,157 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type injectStore interface {
+	Find(id string) string
+}
+
+type injectMemStore map[string]string
+
+func (s injectMemStore) Find(id string) string {
+	return s[id]
+}
+
+type injectConfig struct {
+	Greeting string
+}
+
+type injectUserCtrl struct {
+	URI     `value:"/inject"`
+	Store   injectStore   `inject:""`
+	Config  *injectConfig `inject:"config"`
+	Version string
+}
+
+func (c *injectUserCtrl) User(ps struct {
+	URI `value:"/user/{id}"`
+	Id  string
+}) interface{} {
+	return Content{String: c.Config.Greeting + " " + c.Store.Find(ps.Id) + " " + c.Version}
+}
+
+type injectScratchConfig struct {
+	Admin bool
+}
+
+type injectScratchCtrl struct {
+	URI  `value:"/scratch"`
+	Role string
+	Cfg  *injectScratchConfig
+	Name string
+}
+
+func (c *injectScratchCtrl) Who(ps struct {
+	URI `value:"/who"`
+	PostMethod
+	GetMethod
+}) interface{} {
+	if c.Cfg.Admin {
+		return Content{String: "admin"}
+	}
+	return Content{String: c.Role + " " + c.Name}
+}
+
+type injectMissingCtrl struct {
+	URI    `value:"/missing"`
+	Config *injectConfig `inject:""`
+	Store  injectStore   `inject:"store"`
+	Other  string        `inject:"config"`
+}
+
+func (c *injectMissingCtrl) Ping(URI) interface{} {
+	return Content{String: "pong"}
+}
+
+func TestCan_Service(t *testing.T) {
+	can := NewCan().
+		Service(injectMemStore{"1": "cango"}).
+		Service(&injectConfig{Greeting: "hello"}, "config").
+		Route(&injectUserCtrl{Version: "v1"})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url      string
+		wantBody string
+	}{
+		{url: "/inject/user/1", wantBody: "hello cango v1"},
+		{url: "/inject/user/2?version=v2", wantBody: "hello  v1"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.wantBody {
+			t.Errorf("ServeHTTP(%v) = %v %v, want %v", tt.url, rec.Code, rec.Body.String(), tt.wantBody)
+		}
+	}
+}
+
+func TestCan_Service_nil(t *testing.T) {
+	for _, svc := range []interface{}{nil, (*injectConfig)(nil)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Service(%#v) should panic", svc)
+				}
+			}()
+			NewCan().Service(svc)
+		}()
+	}
+}
+
+func TestCan_Validate_inject(t *testing.T) {
+	can := NewCan().
+		Service(&injectConfig{}, "config").
+		Service(&injectConfig{}, "other").
+		Route(&injectMissingCtrl{})
+	err := can.Validate()
+	if err == nil {
+		t.Fatal("Validate() error = nil")
+	}
+	for _, want := range []string{
+		"injectMissingCtrl.Config: 2 services assignable to *cango.injectConfig",
+		"injectMissingCtrl.Store: no service named store",
+		"injectMissingCtrl.Other: service config is *cango.injectConfig, not assignable to string",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate() error = %v, want %v", err, want)
+		}
+	}
+}
+
+// 请求中的值绑定到接收者上没有预设的字段，预设的字段和共享的依赖不被修改
+func TestCan_ServeHTTP_receiverBound(t *testing.T) {
+	cfg := &injectScratchConfig{}
+	can := NewCan().Route(&injectScratchCtrl{Role: "reader", Cfg: cfg})
+	can.build()
+	tests := []struct {
+		method   string
+		url      string
+		body     string
+		wantBody string
+	}{
+		{method: http.MethodGet, url: "/scratch/who?role=admin&Role=admin&name=tom", wantBody: "reader tom"},
+		{method: http.MethodPost, url: "/scratch/who", body: `{"Role":"admin","Cfg":{"Admin":true},"Name":"jerry"}`, wantBody: "reader jerry"},
+		{method: http.MethodGet, url: "/scratch/who", wantBody: "reader "},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+		if tt.body != "" {
+			req.Header.Set("Content-Type", mimeJSON)
+		}
+		rec := httptest.NewRecorder()
+		can.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Body.String() != tt.wantBody {
+			t.Errorf("ServeHTTP(%v %v) = %v %v, want %v", tt.method, tt.url, rec.Code, rec.Body.String(), tt.wantBody)
+		}
+	}
+	if cfg.Admin {
+		t.Error("shared config is modified by the request body")
+	}
+}
@@ -14,21 +14,36 @@
 package cango
 
 import (
+	"net/http"
 	"reflect"
+	"time"
 )
 
 const (
 	invokeBySelf     = 0
 	invokeByReceiver = 1
 	invokeByFilter   = 2
+	invokeByHandler  = 3
 )
 
 // Invoker 实际执行请求的函数
 // kind用来表示是通过struct来注册的还是只是通过函数来注册的
 // 0 invokeBySelf --- 通过函数
 // 1 invokeByReceiver --- 通过struct
+// 3 invokeByHandler --- 通过Mount挂载的http.Handler
 type Invoker struct {
 	kind int
 	*reflect.Method
-	filter Filter
+	filter  Filter
+	handler http.Handler
+	// plan 参数绑定计划，为nil时在请求中获取
+	plan *bindPlan
+	// receiver 控制器的原型，每个请求使用它的副本做为接收者
+	receiver reflect.Value
+	// bindable 接收者上可以绑定请求中的值的字段，见 bindableFields
+	bindable []int
+	// providers 由Provider生成的参数，key为参数的位置
+	providers map[int]reflect.Value
+	// timeout URI字段上timeout标签限定的执行时间，为0时不限定
+	timeout time.Duration
 }
@@ -0,0 +1,93 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// PathPolicy 请求路径不规范时（如 /a/b/、/a//b、/a/./b）的处理方式
+// 注册的路由路径都是清理过的，不会以/结尾，规范路径即清理之后的路径
+type PathPolicy int
+
+const (
+	// PathLenient 默认方式，使用清理之后的路径再次匹配，不规范的路径也能访问
+	PathLenient PathPolicy = iota
+	// PathStrict 只有规范的路径才能匹配，其余的返回404
+	PathStrict
+	// PathRedirect 重定向到规范的路径，GET和HEAD使用301，其它方法使用308以保留请求方法和body
+	PathRedirect
+)
+
+// SetPathPolicy 设置不规范路径的处理方式，也可以通过 Opts.PathPolicy 设置
+// 挂载（Mount）的handler收到原始的请求路径，由它自己处理
+func (can *Can) SetPathPolicy(policy PathPolicy) *Can {
+	can.pathPolicy = policy
+	return can
+}
+
+// canonicalPath 返回规范的请求路径，对于 * 等不以/开头的路径原样返回
+func canonicalPath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		return p
+	}
+	return path.Clean(p)
+}
+
+// checkPath 按照路径策略处理不规范的路径，返回true表示请求已经处理完毕
+// 挂载（Mount）的路由不使用路径策略
+func (can *Can) checkPath(table *routeTable, rw http.ResponseWriter, r *http.Request) bool {
+	if can.pathPolicy == PathLenient {
+		return false
+	}
+	canonical := canonicalPath(r.URL.Path)
+	if canonical == r.URL.Path {
+		return false
+	}
+	// 挂载的handler自己决定路径的处理方式，如http.ServeMux会把/a重定向到/a/
+	if match := matchRequest(table, r); match.Error() == nil && match.Forwarder().GetInvoker().kind == invokeByHandler {
+		return false
+	}
+	if can.pathPolicy == PathRedirect && hasRoute(table, r, canonical) {
+		code := http.StatusPermanentRedirect
+		if r.Method == http.MethodGet || r.Method == http.MethodHead {
+			code = http.StatusMovedPermanently
+		}
+		target := &url.URL{Path: canonical, RawQuery: r.URL.RawQuery}
+		http.Redirect(rw, r, target.String(), code)
+		return true
+	}
+	if can.fallbackHandler != nil {
+		can.fallbackHandler.ServeHTTP(rw, r)
+		return true
+	}
+	errorHandleMap[http.StatusNotFound](rw, r)
+	return true
+}
+
+// hasRoute 判断规范路径上是否有路由，方法不匹配也算作有
+func hasRoute(table *routeTable, r *http.Request, canonical string) bool {
+	originalPath := r.URL.Path
+	r.URL.Path = canonical
+	defer func() { r.URL.Path = originalPath }()
+	err := matchRequest(table, r).Error()
+	if _, ok := err.(*methodNotAllowedError); ok {
+		return true
+	}
+	return err == nil
+}
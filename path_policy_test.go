@@ -0,0 +1,76 @@
+package cango
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCan_ServeHTTP_pathPolicy(t *testing.T) {
+	tests := []struct {
+		policy       PathPolicy
+		method       string
+		url          string
+		wantCode     int
+		wantLocation string
+	}{
+		{policy: PathLenient, method: http.MethodGet, url: "/allow/me/", wantCode: http.StatusOK},
+		{policy: PathLenient, method: http.MethodGet, url: "/allow//me", wantCode: http.StatusOK},
+		{policy: PathStrict, method: http.MethodGet, url: "/allow/me", wantCode: http.StatusOK},
+		{policy: PathStrict, method: http.MethodGet, url: "/allow/me/", wantCode: http.StatusNotFound},
+		{policy: PathStrict, method: http.MethodPost, url: "/allow//12", wantCode: http.StatusNotFound},
+		{policy: PathRedirect, method: http.MethodGet, url: "/allow/me", wantCode: http.StatusOK},
+		{policy: PathRedirect, method: http.MethodGet, url: "/allow/me/?a=1", wantCode: http.StatusMovedPermanently, wantLocation: "/allow/me?a=1"},
+		{policy: PathRedirect, method: http.MethodHead, url: "/allow/./me", wantCode: http.StatusMovedPermanently, wantLocation: "/allow/me"},
+		{policy: PathRedirect, method: http.MethodPost, url: "/allow//12", wantCode: http.StatusPermanentRedirect, wantLocation: "/allow/12"},
+		{policy: PathRedirect, method: http.MethodGet, url: "/none/", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.url, func(t *testing.T) {
+			can := NewCan().Route(&allowCtrl{}).SetPathPolicy(tt.policy)
+			can.build()
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("ServeHTTP() Location = %v, want %v", got, tt.wantLocation)
+			}
+		})
+	}
+}
+
+func TestCan_ServeHTTP_pathPolicyMount(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("index"))
+	})
+	tests := []struct {
+		url          string
+		wantCode     int
+		wantBody     string
+		wantLocation string
+	}{
+		{url: "/debug/pprof/", wantCode: http.StatusOK, wantBody: "index"},
+		// 由http.ServeMux自己重定向，不会和路径策略来回重定向
+		{url: "/debug/pprof", wantCode: http.StatusMovedPermanently, wantLocation: "/debug/pprof/"},
+	}
+	for _, policy := range []PathPolicy{PathLenient, PathStrict, PathRedirect} {
+		can := NewCan().Mount("/debug/pprof", mux).SetPathPolicy(policy)
+		can.build()
+		for _, tt := range tests {
+			t.Run(fmt.Sprint(policy, tt.url), func(t *testing.T) {
+				rec := httptest.NewRecorder()
+				can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+				if rec.Code != tt.wantCode || rec.Header().Get("Location") != tt.wantLocation {
+					t.Errorf("ServeHTTP() = %v %v, want %v %v", rec.Code, rec.Header().Get("Location"), tt.wantCode, tt.wantLocation)
+				}
+				if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+					t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+				}
+			})
+		}
+	}
+}
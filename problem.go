@@ -0,0 +1,117 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/JessonChan/canlog"
+)
+
+const mimeProblemJSON = "application/problem+json"
+
+// Problem RFC 7807 定义的错误描述，以 application/problem+json 返回
+// 可以做为处理函数的返回值，也可以做为 (T, error) 中的error返回
+// 框架自身的404/405/400/500也使用这种格式，请求的Accept中有text/html时返回文本
+type Problem struct {
+	// Type 错误类型的URI，为空时等同于 about:blank
+	Type string
+	// Title 错误类型的简短描述，为空时使用状态码对应的描述
+	Title    string
+	Status   int
+	Detail   string
+	Instance string
+	// Extensions 扩展字段，和标准字段平级输出，同名时标准字段优先
+	Extensions map[string]interface{}
+}
+
+// NewProblem 生成一个Problem，Title为状态码对应的描述
+func NewProblem(status int, detail string) Problem {
+	return Problem{Title: http.StatusText(status), Status: status, Detail: detail}
+}
+
+func (p Problem) Error() string {
+	if p.Detail != "" {
+		return p.Title + ": " + p.Detail
+	}
+	return p.Title
+}
+
+// StatusCode 实现HTTPError
+func (p Problem) StatusCode() int {
+	return p.Status
+}
+
+// MarshalJSON 扩展字段和标准字段输出在同一层，空的标准字段不输出
+func (p Problem) MarshalJSON() ([]byte, error) {
+	m := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		m[k] = v
+	}
+	for k, v := range map[string]string{"type": p.Type, "title": p.Title, "detail": p.Detail, "instance": p.Instance} {
+		if v != "" {
+			m[k] = v
+		} else {
+			delete(m, k)
+		}
+	}
+	if p.Status != 0 {
+		m["status"] = p.Status
+	} else {
+		delete(m, "status")
+	}
+	return json.Marshal(m)
+}
+
+// writeProblem 以 application/problem+json 返回错误，没有状态码时为500
+func writeProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	if p.Status == 0 {
+		p.Status = http.StatusInternalServerError
+	}
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+	if p.Instance == "" && r != nil && r.URL != nil {
+		p.Instance = r.URL.Path
+	}
+	bs, err := json.Marshal(p)
+	if err != nil {
+		canlog.CanError(err)
+		bs = []byte(`{"status":` + strconv.Itoa(p.Status) + `}`)
+	}
+	w.Header().Set("Content-Type", mimeProblemJSON)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(bs)
+}
+
+// problemHandler 框架默认的错误处理，浏览器请求返回文本，其它返回problem+json
+func problemHandler(code int) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if acceptHTML(r) {
+			http.Error(w, http.StatusText(code), code)
+			return
+		}
+		writeProblem(w, r, NewProblem(code, ""))
+	}
+}
+
+// acceptHTML 判断请求是否希望返回页面
+func acceptHTML(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/html")
+}
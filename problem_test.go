@@ -0,0 +1,85 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type problemCtrl struct {
+	URI `value:"/problem"`
+}
+
+func (c *problemCtrl) Order(ps struct {
+	URI `value:"/order"`
+}) interface{} {
+	p := NewProblem(http.StatusConflict, "order is locked")
+	p.Type = "https://example.com/probs/locked"
+	p.Extensions = map[string]interface{}{"orderId": 7, "status": "ignored"}
+	return p
+}
+
+func (c *problemCtrl) Panic(ps struct {
+	URI `value:"/panic"`
+}) interface{} {
+	panic("boom")
+}
+
+func (c *problemCtrl) Create(ps struct {
+	URI  `value:"/create"`
+	Name string `json:"name"`
+	PostMethod
+}) interface{} {
+	return Content{String: ps.Name}
+}
+
+func TestCan_ServeHTTP_problem(t *testing.T) {
+	can := NewCan().Route(&problemCtrl{})
+	can.build()
+	tests := []struct {
+		name     string
+		method   string
+		url      string
+		accept   string
+		body     string
+		wantCode int
+		wantType string
+		wantBody string
+	}{
+		{name: "return", method: http.MethodGet, url: "/problem/order", wantCode: http.StatusConflict, wantType: mimeProblemJSON,
+			wantBody: `{"detail":"order is locked","instance":"/problem/order","orderId":7,"status":409,"title":"Conflict","type":"https://example.com/probs/locked"}`},
+		{name: "404", method: http.MethodGet, url: "/problem/none", wantCode: http.StatusNotFound, wantType: mimeProblemJSON,
+			wantBody: `{"instance":"/problem/none","status":404,"title":"Not Found"}`},
+		{name: "404 html", method: http.MethodGet, url: "/problem/none", accept: "text/html", wantCode: http.StatusNotFound, wantType: "text/plain; charset=utf-8",
+			wantBody: "Not Found\n"},
+		{name: "405", method: http.MethodPut, url: "/problem/order", wantCode: http.StatusMethodNotAllowed, wantType: mimeProblemJSON,
+			wantBody: `{"instance":"/problem/order","status":405,"title":"Method Not Allowed"}`},
+		{name: "500", method: http.MethodGet, url: "/problem/panic", wantCode: http.StatusInternalServerError, wantType: mimeProblemJSON,
+			wantBody: `{"instance":"/problem/panic","status":500,"title":"Internal Server Error"}`},
+		{name: "400", method: http.MethodPost, url: "/problem/create", body: `{"name":`, wantCode: http.StatusBadRequest, wantType: mimeProblemJSON,
+			wantBody: `{"detail":"invalid json body","instance":"/problem/create","status":400,"title":"Bad Request"}`},
+		{name: "json", method: http.MethodPost, url: "/problem/create", body: `{"name":"cango"}`, wantCode: http.StatusOK,
+			wantBody: "cango"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", mimeJSON)
+			}
+			req.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if got := rec.Header().Get("Content-Type"); tt.wantType != "" && got != tt.wantType {
+				t.Errorf("ServeHTTP() Content-Type = %v, want %v", got, tt.wantType)
+			}
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"reflect"
+	"strings"
+)
+
+// providerMap 参数类型到Provider的映射
+type providerMap map[reflect.Type]reflect.Value
+
+var webRequestPtrType = reflect.TypeOf(&WebRequest{})
+
+// Provide 注册参数的Provider，形如 func(*cango.WebRequest) (T, error)
+// 处理函数中类型为T的参数由Provider生成，不再从请求中解析
+// Provider返回的error会中止请求，状态码和处理函数返回的error一样处理，见 SetErrorMapper
+// 路由上的Provider（ProvideFor）优先于分组上的，分组上的优先于全局的
+// context.Context 参数默认使用请求的context，见 contextProvider
+// 在运行中调用时会立即生效
+func (can *Can) Provide(providers ...interface{}) *Can {
+	can.register(func() {
+		can.providers = can.providers.add(providers)
+	})
+	return can
+}
+
+// ProvideFor 注册只在某个处理函数上生效的Provider，handler为路由名，和URLFor中使用的一致
+// 如 UserCtrl.Profile，也可以是完整的 github.com/xx/ctrl.UserCtrl.Profile
+// 多个名字都能匹配时，使用最长（最具体）的那个
+func (can *Can) ProvideFor(handler string, providers ...interface{}) *Can {
+	can.register(func() {
+		if can.routeProviders == nil {
+			can.routeProviders = map[string]providerMap{}
+		}
+		can.routeProviders[handler] = can.routeProviders[handler].add(providers)
+	})
+	return can
+}
+
+// Provide 注册只在本分组（包括嵌套的分组）的路由上生效的Provider，内层分组的优先
+func (g *Group) Provide(providers ...interface{}) *Group {
+	g.can.register(func() {
+		g.providers = g.providers.add(providers)
+	})
+	return g
+}
+
+func (pm providerMap) add(providers []interface{}) providerMap {
+	if pm == nil {
+		pm = providerMap{}
+	}
+	for _, p := range providers {
+		fv := reflect.ValueOf(p)
+		ft := fv.Type()
+		if ft.Kind() != reflect.Func || ft.NumIn() != 1 || ft.In(0) != webRequestPtrType || ft.NumOut() != 2 || ft.Out(1) != errorType {
+			panic("provider must be func(*cango.WebRequest) (T, error), got " + ft.String())
+		}
+		pm[ft.Out(0)] = fv
+	}
+	return pm
+}
+
+func (pm providerMap) copy() providerMap {
+	if pm == nil {
+		return nil
+	}
+	cp := make(providerMap, len(pm))
+	for k, v := range pm {
+		cp[k] = v
+	}
+	return cp
+}
+
+// provider 从内层到外层查找分组上的Provider
+func (g *Group) provider(typ reflect.Type) (reflect.Value, bool) {
+	if g == nil {
+		return reflect.Value{}, false
+	}
+	if p, ok := g.providers[typ]; ok {
+		return p, true
+	}
+	return g.parent.provider(typ)
+}
+
+// argProviders 构建路由时确定处理函数的每个参数使用的Provider，key为参数的位置
+func (can *Can) argProviders(g *Group, name string, fnType reflect.Type, invokeByWho int) map[int]reflect.Value {
+	var providers map[int]reflect.Value
+	// 通过结构体注册时第一个参数是接收者
+	for i := invokeByWho; i < fnType.NumIn(); i++ {
+		typ := fnType.In(i)
+		p, ok := can.routeProvider(name, typ)
+		if !ok {
+			p, ok = g.provider(typ)
+		}
+		if !ok {
+			p, ok = can.providers[typ]
+		}
+		if !ok && typ == contextType {
+			p, ok = contextProvider, true
+		}
+		if !ok {
+			continue
+		}
+		if providers == nil {
+			providers = map[int]reflect.Value{}
+		}
+		providers[i] = p
+	}
+	return providers
+}
+
+// routeProvider 查找路由上的Provider，完整的路由名优先，其次是匹配的最长后缀
+// 如 UserCtrl.Profile 优先于 Profile
+func (can *Can) routeProvider(name string, typ reflect.Type) (reflect.Value, bool) {
+	if p, ok := can.routeProviders[name][typ]; ok {
+		return p, true
+	}
+	var found reflect.Value
+	matched := ""
+	for handler, pm := range can.routeProviders {
+		if len(handler) <= len(matched) || !strings.HasSuffix(name, "."+handler) {
+			continue
+		}
+		if p, ok := pm[typ]; ok {
+			found, matched = p, handler
+		}
+	}
+	return found, matched != ""
+}
+
+// provide 调用Provider生成参数
+func provide(p reflect.Value, request *WebRequest) (reflect.Value, error) {
+	outs := p.Call([]reflect.Value{reflect.ValueOf(request)})
+	if err, _ := outs[1].Interface().(error); err != nil {
+		return reflect.Value{}, err
+	}
+	return outs[0], nil
+}
@@ -0,0 +1,112 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type providerUser struct {
+	Name string
+}
+
+type providerUserCtrl struct {
+	URI `value:"/provide"`
+}
+
+func (c *providerUserCtrl) Me(ps struct {
+	URI `value:"/me"`
+}, user *providerUser) interface{} {
+	return Content{String: user.Name}
+}
+
+type providerAdminCtrl struct {
+	URI `value:"/admin"`
+}
+
+func (c *providerAdminCtrl) Me(ps struct {
+	URI `value:"/me"`
+}, user *providerUser) interface{} {
+	return Content{String: user.Name}
+}
+
+func (c *providerAdminCtrl) Root(ps struct {
+	URI `value:"/root"`
+}, user *providerUser) interface{} {
+	return Content{String: user.Name}
+}
+
+func TestCan_Provide(t *testing.T) {
+	can := NewCan().Provide(func(r *WebRequest) (*providerUser, error) {
+		name := r.Request.Header.Get("X-User")
+		if name == "" {
+			return nil, NewHTTPError(http.StatusUnauthorized, "login required")
+		}
+		return &providerUser{Name: name}, nil
+	}).Route(&providerUserCtrl{})
+	can.Group("/g", func(g *Group) {
+		g.Provide(func(r *WebRequest) (*providerUser, error) {
+			return &providerUser{Name: "group"}, nil
+		}).Route(&providerAdminCtrl{})
+	})
+	can.ProvideFor("providerAdminCtrl.Root", func(r *WebRequest) (*providerUser, error) {
+		return &providerUser{Name: "route"}, nil
+	})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url      string
+		user     string
+		wantCode int
+		wantBody string
+	}{
+		{url: "/provide/me", user: "cango", wantCode: http.StatusOK, wantBody: "cango"},
+		{url: "/provide/me", wantCode: http.StatusUnauthorized, wantBody: `{"detail":"login required","instance":"/provide/me","status":401,"title":"Unauthorized"}`},
+		{url: "/g/admin/me", wantCode: http.StatusOK, wantBody: "group"},
+		{url: "/g/admin/root", wantCode: http.StatusOK, wantBody: "route"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url+tt.user, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Header.Set("X-User", tt.user)
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode || rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() = %v %v, want %v %v", rec.Code, rec.Body.String(), tt.wantCode, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCan_ProvideFor_overlap(t *testing.T) {
+	named := func(name string) func(r *WebRequest) (*providerUser, error) {
+		return func(r *WebRequest) (*providerUser, error) {
+			return &providerUser{Name: name}, nil
+		}
+	}
+	// 多次构建，保证重叠的后缀每次都选择最具体的那个
+	for i := 0; i < 20; i++ {
+		can := NewCan().Route(&providerUserCtrl{}, &providerAdminCtrl{})
+		can.ProvideFor("Me", named("me")).ProvideFor("providerAdminCtrl.Me", named("admin"))
+		can.build()
+		for url, want := range map[string]string{"/provide/me": "me", "/admin/me": "admin"} {
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+			if rec.Body.String() != want {
+				t.Fatalf("ServeHTTP(%v) = %v, want %v", url, rec.Body.String(), want)
+			}
+		}
+	}
+}
+
+func TestCan_Provide_invalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Provide() should panic")
+		}
+	}()
+	NewCan().Provide(func(r *http.Request) (*providerUser, error) {
+		return nil, nil
+	})
+}
@@ -15,26 +15,79 @@
 package cango
 
 import (
+	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"runtime"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/JessonChan/canlog"
 )
 
 const emptyPrefix = ""
+const emptyHost = ""
 
+// routeDispatcher 注册的路由信息，修改时需要持有Can.mu
 type routeDispatcher struct {
-	dispatcher
 	ctrlEntryMap map[string]ctrlEntry
 }
 
-// Route todo route by controller and method Name???
-// Route 路由结构体上所有的可导出方法
+// routeTable 由注册信息构建出来的路由表，构建之后不再修改
+// 运行时增删路由会构建新的路由表并原子替换，正在处理的请求使用的依然是旧的路由表
+type routeTable struct {
+	dispatcher
+	// routes 记录构建出来的每一条路由，用于冲突检查和路由表输出
+	routes []*routeRecord
+	// filters 按执行顺序排列的filter，包括分组的filter
+	filters []*filterDispatcher
+	// injectErrors 构建时无法注入的控制器依赖
+	injectErrors []string
+}
+
+// emptyRouteTable 还没有构建时使用的路由表
+var emptyRouteTable = &routeTable{dispatcher: newCanMux()}
+
+// RouteSource 路由的来源
+type RouteSource string
+
+const (
+	// RouteSourceController 通过Route注册的结构体
+	RouteSourceController RouteSource = "controller"
+	// RouteSourceFunc 通过RouteFunc注册的函数
+	RouteSourceFunc RouteSource = "func"
+	// RouteSourceStatic 静态文件
+	RouteSourceStatic RouteSource = "static"
+	// RouteSourceMount 通过Mount挂载的http.Handler
+	RouteSourceMount RouteSource = "mount"
+)
+
+// routeRecord 一个处理标识在一条路径上注册的方法
+type routeRecord struct {
+	name    string
+	path    string
+	methods []string
+	source  RouteSource
+	// param 处理函数中带有URI的参数类型
+	param reflect.Type
+	// resp 通过Handle注册时的返回值类型
+	resp reflect.Type
+	// host 路由限定的host模式，为空时不限定
+	host string
+	// group 路由所在的分组，不在分组中时为nil
+	group *Group
+	// tags 构建时分组的标签
+	tags []string
+	// invoker 实际处理请求的函数
+	invoker *Invoker
+}
+
+// Route 路由结构体上所有的可导出方法，根据结构体和方法名定义路由见 RouteConvention
+// 每个请求使用传入实例的副本做为接收者，请求中的值绑定到没有设置的字段上，实例上已经设置的字段和inject标签的字段不会被覆盖，依赖注入见 Service
+// 在运行中调用时会立即生效
 func (can *Can) Route(uris ...URI) *Can {
 	return can.RouteWithPrefix(emptyPrefix, uris...)
 }
@@ -42,15 +95,19 @@ func (can *Can) Route(uris ...URI) *Can {
 // RouteWithPrefix todo route with suffix and simplify
 // Route路由结构体上所有的可导出方法，并使用路由前缀
 func (can *Can) RouteWithPrefix(prefix string, uris ...URI) *Can {
-	for _, uri := range uris {
-		can.route(prefix, uri)
-	}
+	can.register(func() {
+		for _, uri := range uris {
+			can.route(nil, prefix, uri, RouteSourceController)
+		}
+	})
 	return can
 }
 
-func (can *Can) route(prefix string, uri URI) {
+func (can *Can) route(g *Group, prefix string, uri URI, source RouteSource) {
+	host, prefix := g.scope(prefix)
 	typ := toPtrKind(uri)
-	can.routeMux.ctrlEntryMap[prefix+typ.String()] = ctrlEntry{prefix: prefix, kind: reflect.Ptr, ctrl: uri, tim: time.Now().Unix()}
+	delete(can.removedURIs, typ)
+	can.routeMux.ctrlEntryMap[host+prefix+typ.String()] = ctrlEntry{group: g, host: host, prefix: prefix, kind: reflect.Ptr, ctrl: uri, source: source, tim: time.Now().Unix(), seq: nextCtrlEntrySeq()}
 }
 
 // RouteFunc 方法路由，可以传入多个方法
@@ -60,13 +117,16 @@ func (can *Can) RouteFunc(fns ...interface{}) *Can {
 
 // RouteFuncWithPrefix 带有前缀的方法路由，可以传入多个方法（便于版本、分组等管理）
 func (can *Can) RouteFuncWithPrefix(prefix string, fns ...interface{}) *Can {
-	for _, fn := range fns {
-		can.routeFunc(prefix, fn)
-	}
+	can.register(func() {
+		for _, fn := range fns {
+			can.routeFunc(nil, prefix, fn, RouteSourceFunc)
+		}
+	})
 	return can
 }
 
-func (can *Can) routeFunc(prefix string, fn interface{}) {
+func (can *Can) routeFunc(g *Group, prefix string, fn interface{}, source RouteSource) {
+	host, prefix := g.scope(prefix)
 	fv := reflect.ValueOf(fn)
 	if fv.Kind() != reflect.Func {
 		canlog.CanInfo("can't forwarder func with ", fv.Kind())
@@ -79,7 +139,7 @@ func (can *Can) routeFunc(prefix string, fn interface{}) {
 		Func:    fv,
 		Index:   0,
 	}
-	can.routeMux.ctrlEntryMap[prefix+fv.String()] = ctrlEntry{prefix: prefix, kind: reflect.Func, fn: funcMethod, tim: time.Now().Unix()}
+	can.routeMux.ctrlEntryMap[host+prefix+fv.String()] = ctrlEntry{group: g, host: host, prefix: prefix, kind: reflect.Func, fn: funcMethod, source: source, tim: time.Now().Unix(), seq: nextCtrlEntrySeq()}
 }
 
 var uriRegMap = map[URI]string{}
@@ -97,27 +157,203 @@ func RegisterURIWithPrefix(prefix string, uri URI, cangoName ...string) bool {
 }
 
 type ctrlEntry struct {
+	group  *Group
+	host   string
 	prefix string
 	kind   reflect.Kind
 	ctrl   interface{}
 	fn     reflect.Method
-	tim    int64
+	// handler 通过Mount挂载的http.Handler，已经处理了前缀的去除
+	handler http.Handler
+	// methods 和 resp 只有通过Handle注册时才有，路径就是prefix
+	methods []string
+	resp    reflect.Type
+	source  RouteSource
+	tim     int64
+	// seq 注册顺序，同一秒内注册的路由按照它来排序，保证路由构建的顺序是确定的
+	seq int64
+}
+
+var ctrlEntrySeq int64
+
+func nextCtrlEntrySeq() int64 {
+	return atomic.AddInt64(&ctrlEntrySeq, 1)
 }
 
 type sortCtrlEntry []ctrlEntry
 
-func (s sortCtrlEntry) Len() int           { return len(s) }
-func (s sortCtrlEntry) Less(i, j int) bool { return s[i].tim < s[j].tim }
-func (s sortCtrlEntry) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s sortCtrlEntry) Len() int { return len(s) }
+func (s sortCtrlEntry) Less(i, j int) bool {
+	if s[i].tim == s[j].tim {
+		return s[i].seq < s[j].seq
+	}
+	return s[i].tim < s[j].tim
+}
+func (s sortCtrlEntry) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+// build 根据已经注册的信息重新构建路由和filter并替换路由表，可以重复调用
+func (can *Can) build() *routeTable {
+	can.mu.Lock()
+	defer can.mu.Unlock()
+	t := can.compile()
+	can.table.Store(t)
+	return t
+}
 
-func (can *Can) buildRoute() {
+// register 在锁中修改注册信息，已经构建过路由表时重新构建并检查，检查通过之后才替换路由表
+// 检查失败时撤销这次对路由和依赖的修改，继续使用原来的路由表，错误可以通过Err获取
+func (can *Can) register(fn func()) error {
+	can.mu.Lock()
+	defer can.mu.Unlock()
+	if can.table.Load() == nil {
+		fn()
+		return nil
+	}
+	snap := can.snapshot()
+	fn()
+	t := can.compile()
+	err := checkRoutes(t)
+	if err == nil {
+		err = checkInject(t)
+	}
+	if err == nil {
+		err = can.checkTpl(t)
+	}
+	can.err = err
+	if err != nil {
+		can.restore(snap)
+		canlog.CanError(err)
+		return err
+	}
+	can.table.Store(t)
+	return nil
+}
+
+// Err 返回运行中最后一次修改路由的错误，修改生效时为nil
+func (can *Can) Err() error {
+	can.mu.Lock()
+	defer can.mu.Unlock()
+	return can.err
+}
+
+// registration compile用到的所有注册信息，用于撤销失败的修改
+type registration struct {
+	ctrlEntryMap     map[string]ctrlEntry
+	removedURIs      map[reflect.Type]bool
+	removedFilters   map[FilterType]bool
+	filterDispatcher map[FilterType]*filterDispatcher
+	filterURITypes   map[*filterDispatcher][]reflect.Type
+	services         []service
+	providers        providerMap
+	routeProviders   map[string]providerMap
+	routeNaming      RouteNaming
+	debugRoutes      bool
+	staticWalked     string
+	groups           []groupState
+}
+
+// groupState 分组上可以被修改的信息
+type groupState struct {
+	group     *Group
+	filters   []Filter
+	tags      []string
+	providers providerMap
+}
+
+func (can *Can) snapshot() registration {
+	snap := registration{
+		ctrlEntryMap:     make(map[string]ctrlEntry, len(can.routeMux.ctrlEntryMap)),
+		removedURIs:      make(map[reflect.Type]bool, len(can.removedURIs)),
+		removedFilters:   make(map[FilterType]bool, len(can.removedFilters)),
+		filterDispatcher: make(map[FilterType]*filterDispatcher, len(can.filterDispatcher)),
+		filterURITypes:   make(map[*filterDispatcher][]reflect.Type, len(can.filterDispatcher)),
+		services:         can.services[:len(can.services):len(can.services)],
+		providers:        can.providers.copy(),
+		routeProviders:   make(map[string]providerMap, len(can.routeProviders)),
+		routeNaming:      can.routeNaming,
+		debugRoutes:      can.debugRoutes,
+		staticWalked:     can.staticWalked,
+	}
+	for k, v := range can.routeMux.ctrlEntryMap {
+		snap.ctrlEntryMap[k] = v
+	}
+	for k, v := range can.removedURIs {
+		snap.removedURIs[k] = v
+	}
+	for k, v := range can.removedFilters {
+		snap.removedFilters[k] = v
+	}
+	for k, fd := range can.filterDispatcher {
+		snap.filterDispatcher[k] = fd
+		snap.filterURITypes[fd] = fd.uriTypes[:len(fd.uriTypes):len(fd.uriTypes)]
+	}
+	for k, pm := range can.routeProviders {
+		snap.routeProviders[k] = pm.copy()
+	}
+	for _, g := range can.groups {
+		snap.groups = append(snap.groups, groupState{
+			group:     g,
+			filters:   g.filters[:len(g.filters):len(g.filters)],
+			tags:      g.tags[:len(g.tags):len(g.tags)],
+			providers: g.providers.copy(),
+		})
+	}
+	return snap
+}
+
+func (can *Can) restore(snap registration) {
+	can.routeMux.ctrlEntryMap = snap.ctrlEntryMap
+	can.removedURIs = snap.removedURIs
+	can.removedFilters = snap.removedFilters
+	can.filterDispatcher = snap.filterDispatcher
+	for fd, types := range snap.filterURITypes {
+		fd.uriTypes = types
+	}
+	can.services = snap.services
+	can.providers = snap.providers
+	can.routeProviders = snap.routeProviders
+	can.routeNaming = snap.routeNaming
+	can.debugRoutes = snap.debugRoutes
+	can.staticWalked = snap.staticWalked
+	for _, gs := range snap.groups {
+		gs.group.filters, gs.group.tags, gs.group.providers = gs.filters, gs.tags, gs.providers
+	}
+}
+
+// compile 根据注册信息构建新的路由表，调用时需要持有mu
+func (can *Can) compile() *routeTable {
+	t := &routeTable{dispatcher: newCanMux()}
+	can.buildStaticRoute()
+	if can.debugRoutes {
+		can.routeFunc(nil, emptyPrefix, can.routesEndpoint, RouteSourceFunc)
+	}
+	can.buildRoute(t)
+	// 务必要先构建route再去构建filter
+	can.buildFilter(t)
+	return t
+}
+
+// loadTable 返回当前生效的路由表
+func (can *Can) loadTable() *routeTable {
+	if t, ok := can.table.Load().(*routeTable); ok {
+		return t
+	}
+	return emptyRouteTable
+}
+
+func (can *Can) buildRoute(t *routeTable) {
 	for uri, nameAndPrefix := range uriRegMap {
 		ss := strings.Split(nameAndPrefix, "|")
 		if len(ss) != 2 {
 			continue
 		}
-		if can.name == ss[0] {
-			can.route(ss[1], uri)
+		typ := toPtrKind(uri)
+		if can.name != ss[0] || can.removedURIs[typ] {
+			continue
+		}
+		// 只加入一次，重新构建时保持第一次加入时的顺序
+		if _, ok := can.routeMux.ctrlEntryMap[ss[1]+typ.String()]; !ok {
+			can.route(nil, ss[1], uri, RouteSourceController)
 		}
 	}
 	var ces []ctrlEntry
@@ -126,13 +362,18 @@ func (can *Can) buildRoute() {
 	}
 	sort.Sort(sortCtrlEntry(ces))
 	for _, ce := range ces {
-		can.buildSingleRoute(ce)
+		can.buildSingleRoute(t, ce)
 	}
 }
 
 // todo 如果static存在的文件非常多的时候，这种实现方式会成为巨大的问题
 // todo 这里也有个优势，就是防止被恶意请求，请求某个不存在的文件，会直接在调用io之前被拒绝
+// 同一个静态文件目录只遍历一次，注册的路由保存在ctrlEntryMap中
 func (can *Can) buildStaticRoute() {
+	if can.staticRootPath == can.staticWalked {
+		return
+	}
+	can.staticWalked = can.staticRootPath
 	if fileInfo, _ := os.Lstat(can.staticRootPath); fileInfo == nil {
 		return
 	}
@@ -140,38 +381,62 @@ func (can *Can) buildStaticRoute() {
 		if info == nil || info.IsDir() {
 			return nil
 		}
-		can.route(filepath.Clean("/"+strings.TrimPrefix(path, can.rootPath)), &staticController{})
+		can.route(nil, filepath.Clean("/"+strings.TrimPrefix(path, can.rootPath)), &staticController{}, RouteSourceStatic)
 		return nil
 	})
 	// todo 特殊处理favicon.ico和robots.txt
-	can.route("/favicon.ico", &staticController{})
-	can.route("/robots.txt", &staticController{})
-	can.routeFunc(strings.TrimPrefix(can.staticRootPath, can.rootPath), func(URI) any {
+	can.route(nil, "/favicon.ico", &staticController{}, RouteSourceStatic)
+	can.route(nil, "/robots.txt", &staticController{}, RouteSourceStatic)
+	can.routeFunc(nil, strings.TrimPrefix(can.staticRootPath, can.rootPath), func(URI) any {
 		return StaticFile{Path: "/index.html"}
-	})
+	}, RouteSourceStatic)
 }
 
-func (can *Can) buildSingleRoute(ce ctrlEntry) {
+func (can *Can) buildSingleRoute(t *routeTable, ce ctrlEntry) {
+	if ce.handler != nil {
+		can.routeHandler(t, ce)
+		return
+	}
+	if ce.resp != nil {
+		can.routeTyped(t, ce)
+		return
+	}
 	switch ce.kind {
 	case reflect.Ptr:
 		hs := factory(ce.ctrl)
 		ctrlTagPaths, ctlName := urlStr(hs.typ.Elem())
+		ctrlTagPaths, convention := can.conventionCtrlPaths(ctrlTagPaths, hs.typ.Elem().Name())
+		host := ce.host
+		if h := tagHost(hs.typ.Elem()); h != "" {
+			host = h
+		}
+		receiver := can.newReceiver(t, ce.ctrl, hs.typ)
+		bindable := bindableFields(receiver)
 		for _, hm := range hs.fns {
-			can.routeMethod(invokeByReceiver, ce.prefix, hm.fn, ctlName+"."+hm.fn.Name, ctrlTagPaths)
+			if invoker := can.routeMethod(t, invokeByReceiver, ce.group, host, ce.prefix, hm.fn, ctlName+"."+hm.fn.Name, ctrlTagPaths, convention, ce.source); invoker != nil {
+				invoker.receiver = receiver
+				invoker.bindable = bindable
+			}
 		}
 	case reflect.Func:
-		can.routeMethod(invokeBySelf, ce.prefix, ce.fn, "RouteFunc."+ce.fn.Name, nil)
+		can.routeMethod(t, invokeBySelf, ce.group, ce.host, ce.prefix, ce.fn, "RouteFunc."+ce.fn.Name, nil, false, ce.source)
 	}
 }
 
 // todo use factory to clean code
-func (can *Can) routeMethod(invokeByWho int, prefix string, m reflect.Method, routerName string, ctrlTagPaths []string) {
+func (can *Can) routeMethod(t *routeTable, invokeByWho int, g *Group, host, prefix string, m reflect.Method, routerName string, ctrlTagPaths []string, convention bool, source RouteSource) *Invoker {
 	hm := factoryMethod(m, invokeByWho)
 	if hm == nil {
-		return
+		return nil
+	}
+	// 方法参数上的host优先于结构体和Host分组上的
+	if hm.host != "" {
+		host = hm.host
 	}
-	for _, hp := range hm.patterns {
-		route := can.routeMux.NewForwarder(routerName, &Invoker{kind: invokeByWho, Method: &m})
+	mux := t.dispatcher.(*canDispatcher).host(host)
+	invoker := &Invoker{kind: invokeByWho, Method: &m, plan: hm.plan, providers: can.argProviders(g, routerName, m.Type, invokeByWho), timeout: hm.timeout}
+	for _, hp := range can.handlePaths(convention, hm) {
+		route := mux.NewForwarder(routerName, invoker)
 		for _, path := range combinePaths(prefix, ctrlTagPaths, hp.path) {
 			// default method is GET
 			httpMethods := defaultHTTPMethods
@@ -179,9 +444,11 @@ func (can *Can) routeMethod(invokeByWho int, prefix string, m reflect.Method, ro
 				httpMethods = hp.httpMethods
 			}
 			route.PathMethods(path, httpMethods...)
+			t.routes = append(t.routes, &routeRecord{name: routerName, path: path, methods: httpMethods, source: source, param: hm.param, host: host, group: g, tags: g.chainTags(), invoker: invoker})
 			canlog.CanDebug(routerName, path, httpMethods)
 		}
 	}
+	return invoker
 }
 
 func combinePaths(prefix string, ctrlTagPaths []string, methodTagPath string) (paths []string) {
@@ -0,0 +1,172 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Validate 构建路由并检查是否有冲突，不需要启动服务，方便在测试中使用
+// 同一路径同一方法被两个处理函数注册，或者两个变量路由形状完全相同（如/user/{id}和/user/{name}）时返回错误
+// 控制器中带有inject标签的字段无法注入时，或者开启了CheckTpl而推断出的模板不存在时也返回错误
+func (can *Can) Validate() error {
+	t := can.build()
+	if err := checkRoutes(t); err != nil {
+		return err
+	}
+	if err := checkInject(t); err != nil {
+		return err
+	}
+	return can.checkTpl(t)
+}
+
+func checkRoutes(t *routeTable) error {
+	if err := checkPatterns(t); err != nil {
+		return err
+	}
+	type shapeRoute struct {
+		*routeRecord
+		method string
+	}
+	shapes := map[string][]shapeRoute{}
+	var keys []string
+	for _, rr := range t.routes {
+		// 静态文件的路由允许被覆盖
+		if rr.source == RouteSourceStatic {
+			continue
+		}
+		shape := patternShape(rr.path)
+		for _, m := range rr.methods {
+			key := m + " " + rr.host + shape
+			if _, ok := shapes[key]; !ok {
+				keys = append(keys, key)
+			}
+			shapes[key] = append(shapes[key], shapeRoute{routeRecord: rr, method: m})
+		}
+	}
+	var conflicts []string
+	for _, key := range keys {
+		srs := shapes[key]
+		for i := 0; i < len(srs); i++ {
+			for j := i + 1; j < len(srs); j++ {
+				a, b := srs[i], srs[j]
+				if a.name == b.name {
+					continue
+				}
+				if a.path == b.path {
+					conflicts = append(conflicts, fmt.Sprintf("duplicate route %s %s: %s and %s", a.method, a.host+a.path, a.name, b.name))
+				} else {
+					conflicts = append(conflicts, fmt.Sprintf("ambiguous route %s %s and %s %s: %s and %s", a.method, a.host+a.path, b.method, b.host+b.path, a.name, b.name))
+				}
+			}
+		}
+	}
+	if len(conflicts) > 0 {
+		return errors.New("cango route conflict:\n\t" + strings.Join(conflicts, "\n\t"))
+	}
+	return nil
+}
+
+// checkPatterns 检查路由和filter中是否有无法解析的pattern，如约束不是合法的正则或者{path...}不在最后一段
+func checkPatterns(t *routeTable) error {
+	var errs []string
+	seen := map[string]bool{}
+	add := func(d dispatcher) {
+		cd, ok := d.(*canDispatcher)
+		if !ok {
+			return
+		}
+		for _, e := range cd.patternErrors() {
+			if !seen[e] {
+				seen[e] = true
+				errs = append(errs, e)
+			}
+		}
+	}
+	add(t.dispatcher)
+	for _, fd := range t.filters {
+		add(fd.dispatcher)
+	}
+	if len(errs) > 0 {
+		return errors.New("cango invalid route pattern:\n\t" + strings.Join(errs, "\n\t"))
+	}
+	return nil
+}
+
+// patternShape 去掉变量名后的路由形状，形状相同的变量路由无法区分
+// 静态路由由mapDispatcher精确匹配，直接使用原路径
+func patternShape(path string) string {
+	if !isVarPattern(path) {
+		return path
+	}
+	words, _, _, err := elementsToWords(parseRoutePattern(path))
+	if err != nil {
+		return path
+	}
+	keys := make([]string, len(words))
+	for i, w := range words {
+		switch {
+		case w.isWildcard:
+			keys[i] = "*"
+		case w.isCatchAll:
+			keys[i] = "{...}"
+		case len(w.glob) > 0:
+			keys[i] = w.key
+		case w.isVar:
+			keys[i] = "{:" + w.constraint + "}"
+		default:
+			keys[i] = w.key
+		}
+	}
+	return "/" + strings.Join(keys, "/")
+}
+
+// routeFilters 返回在这条路由上生效的filter名字
+func (t *routeTable) routeFilters(host, path, method string) []string {
+	req := &http.Request{Method: method, Host: host, URL: &url.URL{Path: path}}
+	var names []string
+	seen := map[string]bool{}
+	for _, fd := range t.filters {
+		name := filterTypeName(fd.filter)
+		if !seen[name] && matchRequest(fd.dispatcher, req).Error() == nil {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printRoutes 输出格式化的路由表
+func (t *routeTable) printRoutes(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "METHOD\tHOST\tPATH\tHANDLER\tFILTERS")
+	for _, rr := range t.routes {
+		if rr.source == RouteSourceStatic {
+			continue
+		}
+		for _, m := range rr.methods {
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", m, rr.host, rr.path, rr.name, strings.Join(t.routeFilters(rr.host, rr.path, m), ","))
+		}
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,103 @@
+package cango
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+type checkUserCtrl struct {
+	URI `value:"/user"`
+}
+
+func (c *checkUserCtrl) Get(ps struct {
+	URI `value:"/{id}"`
+}) interface{} {
+	return nil
+}
+
+func (c *checkUserCtrl) Me(ps struct {
+	URI `value:"/me"`
+}) interface{} {
+	return nil
+}
+
+type checkMemberCtrl struct {
+	URI `value:"/user"`
+}
+
+func (c *checkMemberCtrl) Get(ps struct {
+	URI `value:"/{name}"`
+}) interface{} {
+	return nil
+}
+
+type checkProfileCtrl struct {
+	URI `value:"/user"`
+}
+
+func (c *checkProfileCtrl) Me(ps struct {
+	URI `value:"/me"`
+}) interface{} {
+	return nil
+}
+
+func (c *checkProfileCtrl) Post(ps struct {
+	URI `value:"/{name}"`
+	PostMethod
+}) interface{} {
+	return nil
+}
+
+func TestCan_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		uris     []URI
+		wantErrs []string
+	}{
+		{
+			name: "no_conflict",
+			uris: []URI{&checkUserCtrl{}},
+		},
+		{
+			name:     "duplicate",
+			uris:     []URI{&checkUserCtrl{}, &checkProfileCtrl{}},
+			wantErrs: []string{"duplicate route GET /user/me", "checkUserCtrl.Me", "checkProfileCtrl.Me"},
+		},
+		{
+			name:     "ambiguous",
+			uris:     []URI{&checkUserCtrl{}, &checkMemberCtrl{}},
+			wantErrs: []string{"ambiguous route GET /user/{id} and GET /user/{name}", "checkUserCtrl.Get", "checkMemberCtrl.Get"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewCan().Route(tt.uris...).Validate()
+			if len(tt.wantErrs) == 0 {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Validate() error = nil, want %v", tt.wantErrs)
+			}
+			for _, want := range tt.wantErrs {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Validate() error = %v, want contains %v", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCan_printRoutes(t *testing.T) {
+	can := NewCan().Route(&checkUserCtrl{})
+	bb := &bytes.Buffer{}
+	can.build().printRoutes(bb)
+	for _, want := range []string{"METHOD", "/user/{id}", "checkUserCtrl.Get", "/user/me"} {
+		if !strings.Contains(bb.String(), want) {
+			t.Errorf("printRoutes() = %v, want contains %v", bb.String(), want)
+		}
+	}
+}
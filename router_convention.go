@@ -0,0 +1,136 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// RouteNaming 约定路由中由名字生成路径时的命名方式
+type RouteNaming int
+
+const (
+	// NamingNone 不使用约定路由，默认值
+	NamingNone RouteNaming = iota
+	// NamingKebab UserProfile -> user-profile
+	NamingKebab
+	// NamingSnake UserProfile -> user_profile
+	NamingSnake
+	// NamingCamel UserProfile -> userProfile
+	NamingCamel
+)
+
+// verbPrefixes 方法名前缀对应的请求方法
+var verbPrefixes = map[string]string{
+	"Get":     http.MethodGet,
+	"Post":    http.MethodPost,
+	"Put":     http.MethodPut,
+	"Patch":   http.MethodPatch,
+	"Delete":  http.MethodDelete,
+	"Head":    http.MethodHead,
+	"Options": http.MethodOptions,
+}
+
+// RouteConvention 开启约定路由，没有value标签的结构体和它的方法使用名字生成路径
+// 有value标签的结构体不受影响
+// 结构体名去掉Ctrl/Controller后缀做为路径，如 UserCtrl -> /user
+// 方法名去掉Get/Post/Delete等前缀做为路径，前缀同时决定请求方法，没有前缀时为GET
+// 如 func (c *UserCtrl) GetProfile(cango.URI) 为 GET /user/profile，PostLogin 为 POST /user/login
+// 标签中显式写的路径和请求方法依然优先，RouteFunc注册的函数不受影响
+func (can *Can) RouteConvention(naming RouteNaming) *Can {
+	can.register(func() {
+		can.routeNaming = naming
+	})
+	return can
+}
+
+// conventionCtrlPaths 没有value标签的结构体使用结构体名做为路径，并返回是否使用约定路由
+// 有value标签的结构体保持原来的规则，方法名不会生成路径
+func (can *Can) conventionCtrlPaths(ctrlTagPaths []string, typeName string) ([]string, bool) {
+	if can.routeNaming == NamingNone || len(ctrlTagPaths) > 0 {
+		return ctrlTagPaths, false
+	}
+	name := strings.TrimSuffix(strings.TrimSuffix(typeName, "Controller"), "Ctrl")
+	return []string{"/" + convertName(splitName(name), can.routeNaming)}, true
+}
+
+// handlePaths 返回处理函数的路径，使用约定路由时没有写路径的使用方法名
+func (can *Can) handlePaths(convention bool, hm *handlerMethod) []*handlePath {
+	if !convention {
+		return hm.patterns
+	}
+	words := splitName(hm.fn.Name)
+	verb, hasVerb := "", false
+	if len(words) > 0 {
+		verb, hasVerb = verbPrefixes[words[0]]
+	}
+	if hasVerb {
+		words = words[1:]
+	}
+	var hps []*handlePath
+	for _, hp := range hm.patterns {
+		nhp := &handlePath{path: hp.path, httpMethods: hp.httpMethods}
+		if nhp.path == "" {
+			nhp.path = "/" + convertName(words, can.routeNaming)
+		}
+		// 只使用cango.URI做为参数，或者参数中没有写请求方法时，由方法名决定
+		if hasVerb && (hm.param == uriType || len(hp.httpMethods) == 0) {
+			nhp.httpMethods = []string{verb}
+		}
+		hps = append(hps, nhp)
+	}
+	return hps
+}
+
+// splitName 按照驼峰拆分名字，连续的大写字母做为一个词，如 UserID -> User ID，HTMLPage -> HTML Page
+func splitName(name string) []string {
+	var words []string
+	rs := []rune(name)
+	start := 0
+	for i := 1; i < len(rs); i++ {
+		if !unicode.IsUpper(rs[i]) {
+			continue
+		}
+		if !unicode.IsUpper(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1])) {
+			words = append(words, string(rs[start:i]))
+			start = i
+		}
+	}
+	if start < len(rs) {
+		words = append(words, string(rs[start:]))
+	}
+	return words
+}
+
+func convertName(words []string, naming RouteNaming) string {
+	switch naming {
+	case NamingSnake:
+		return strings.ToLower(strings.Join(words, "_"))
+	case NamingCamel:
+		var sb strings.Builder
+		for i, w := range words {
+			if i == 0 {
+				sb.WriteString(strings.ToLower(w))
+				continue
+			}
+			sb.WriteString(strings.ToUpper(w[:1]) + strings.ToLower(w[1:]))
+		}
+		return sb.String()
+	default:
+		return strings.ToLower(strings.Join(words, "-"))
+	}
+}
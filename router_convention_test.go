@@ -0,0 +1,164 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+type conventionUserCtrl struct {
+	URI
+}
+
+func (c *conventionUserCtrl) GetProfile(URI) interface{} {
+	return nil
+}
+
+func (c *conventionUserCtrl) PostLogin(URI) interface{} {
+	return nil
+}
+
+func (c *conventionUserCtrl) Delete(ps struct {
+	URI `value:"/{id}"`
+}) interface{} {
+	return nil
+}
+
+func (c *conventionUserCtrl) ResetPassword(ps struct {
+	URI
+	PutMethod
+}) interface{} {
+	return nil
+}
+
+func (c *conventionUserCtrl) Getaway(URI) interface{} {
+	return nil
+}
+
+type conventionTagCtrl struct {
+	URI `value:"/account"`
+}
+
+func (c *conventionTagCtrl) GetHTMLPage(URI) interface{} {
+	return nil
+}
+
+func TestCan_RouteConvention(t *testing.T) {
+	tests := []struct {
+		naming RouteNaming
+		want   []string
+	}{
+		{naming: NamingKebab, want: []string{
+			"DELETE /convention-user/{id}",
+			"GET /account",
+			"GET /convention-user/getaway",
+			"GET /convention-user/profile",
+			"POST /convention-user/login",
+			"PUT /convention-user/reset-password",
+		}},
+		{naming: NamingSnake, want: []string{
+			"DELETE /convention_user/{id}",
+			"GET /account",
+			"GET /convention_user/getaway",
+			"GET /convention_user/profile",
+			"POST /convention_user/login",
+			"PUT /convention_user/reset_password",
+		}},
+		{naming: NamingCamel, want: []string{
+			"DELETE /conventionUser/{id}",
+			"GET /account",
+			"GET /conventionUser/getaway",
+			"GET /conventionUser/profile",
+			"POST /conventionUser/login",
+			"PUT /conventionUser/resetPassword",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want[0], func(t *testing.T) {
+			can := NewCan().Route(&conventionUserCtrl{}, &conventionTagCtrl{}).RouteConvention(tt.naming)
+			if err := can.Validate(); err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, ri := range can.Routes() {
+				for _, m := range ri.Methods {
+					got = append(got, m+" "+ri.Path)
+				}
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Routes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	// 不开启时保持原来的行为
+	can := NewCan().Route(&conventionTagCtrl{})
+	can.build()
+	if got := can.Routes(); len(got) != 1 || got[0].Path != "/account" || got[0].Methods[0] != http.MethodGet {
+		t.Errorf("Routes() = %v", got)
+	}
+}
+
+func Test_splitName(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "GetProfile", want: []string{"Get", "Profile"}},
+		{name: "UserID", want: []string{"User", "ID"}},
+		{name: "HTMLPage", want: []string{"HTML", "Page"}},
+		{name: "Get", want: []string{"Get"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitName(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type conventionFilter struct {
+	Filter
+	count *int
+}
+
+func (f *conventionFilter) PreHandle(request *WebRequest) interface{} {
+	*f.count++
+	return true
+}
+
+func TestCan_RouteConvention_filter(t *testing.T) {
+	count := 0
+	can := NewCan().Route(&conventionUserCtrl{}, &conventionTagCtrl{}).RouteConvention(NamingKebab).
+		Filter(&conventionFilter{count: &count}, &conventionUserCtrl{})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{method: http.MethodGet, path: "/convention-user/profile", want: 1},
+		{method: http.MethodDelete, path: "/convention-user/1", want: 2},
+		{method: http.MethodGet, path: "/account", want: 2},
+	}
+	for _, tt := range tests {
+		can.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		if count != tt.want {
+			t.Errorf("%s %s filter count = %v, want %v", tt.method, tt.path, count, tt.want)
+		}
+	}
+	for _, ri := range can.Routes() {
+		want := []string{"conventionFilter"}
+		if ri.Path == "/account" {
+			want = nil
+		}
+		if !reflect.DeepEqual(ri.Filters, want) {
+			t.Errorf("%s Filters = %v, want %v", ri.Path, ri.Filters, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+// Group 路由分组，组内的路由共享前缀、host、filter和标签，分组可以嵌套
+//
+//	can.Group("/admin", func(g *cango.Group) {
+//		g.Use(&AuthFilter{})
+//		g.Route(&UserCtrl{})
+//	})
+type Group struct {
+	can     *Can
+	parent  *Group
+	prefix  string
+	host    string
+	filters []Filter
+	tags    []string
+	// providers 组内生效的参数Provider
+	providers providerMap
+}
+
+// Group 新建一个路由分组，fn 中注册的路由都使用prefix做为前缀
+func (can *Can) Group(prefix string, fn func(g *Group)) *Can {
+	fn(can.newGroup(&Group{can: can, prefix: prefix}))
+	return can
+}
+
+// Host 注册只在某个host下生效的路由，pattern 形如 api.example.com、{tenant}.example.com
+// host中的变量和路径变量一样可以绑定到参数结构体中
+// 也可以在URI字段上使用host标签，如 URI `value:"/user" host:"{tenant}.example.com"`
+// 限定了host的路由优先于不限定host的路由，不会转换为gin的路由
+func (can *Can) Host(pattern string) *Group {
+	return can.newGroup(&Group{can: can, host: pattern})
+}
+
+// Group 新建一个嵌套的分组，继承当前分组的前缀、host、filter和标签
+func (g *Group) Group(prefix string, fn func(g *Group)) *Group {
+	fn(g.can.newGroup(&Group{can: g.can, parent: g, prefix: g.prefix + "/" + prefix, host: g.host}))
+	return g
+}
+
+// newGroup 记录新建的分组，运行中的修改失败时用于撤销分组上的修改
+func (can *Can) newGroup(g *Group) *Group {
+	can.mu.Lock()
+	defer can.mu.Unlock()
+	can.groups = append(can.groups, g)
+	return g
+}
+
+// Use 添加只在本分组（包括嵌套的分组）的路由上生效的filter，外层分组的filter先执行
+// 不论在注册路由之前还是之后调用，都对组内所有的路由生效
+func (g *Group) Use(filters ...Filter) *Group {
+	g.can.register(func() {
+		g.filters = append(g.filters, filters...)
+	})
+	return g
+}
+
+// Tag 给组内的路由打上标签，在Routes中可以看到
+func (g *Group) Tag(tags ...string) *Group {
+	g.can.register(func() {
+		g.tags = append(g.tags, tags...)
+	})
+	return g
+}
+
+// Route 路由结构体上所有的可导出方法
+func (g *Group) Route(uris ...URI) *Group {
+	return g.RouteWithPrefix(emptyPrefix, uris...)
+}
+
+// RouteWithPrefix 路由结构体上所有的可导出方法，并在分组前缀之后再加上prefix
+func (g *Group) RouteWithPrefix(prefix string, uris ...URI) *Group {
+	g.can.register(func() {
+		for _, uri := range uris {
+			g.can.route(g, prefix, uri, RouteSourceController)
+		}
+	})
+	return g
+}
+
+// RouteFunc 方法路由，可以传入多个方法
+func (g *Group) RouteFunc(fns ...interface{}) *Group {
+	return g.RouteFuncWithPrefix(emptyPrefix, fns...)
+}
+
+// RouteFuncWithPrefix 带有前缀的方法路由
+func (g *Group) RouteFuncWithPrefix(prefix string, fns ...interface{}) *Group {
+	g.can.register(func() {
+		for _, fn := range fns {
+			g.can.routeFunc(g, prefix, fn, RouteSourceFunc)
+		}
+	})
+	return g
+}
+
+// chainFilters 从最外层到当前分组的所有filter
+func (g *Group) chainFilters() []Filter {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.chainFilters(), g.filters...)
+}
+
+// chainTags 从最外层到当前分组的所有标签
+func (g *Group) chainTags() []string {
+	if g == nil {
+		return nil
+	}
+	return append(g.parent.chainTags(), g.tags...)
+}
+
+// scope 返回分组的host和完整前缀，分组为空时为不限定host的prefix
+func (g *Group) scope(prefix string) (string, string) {
+	if g == nil {
+		return emptyHost, prefix
+	}
+	if prefix == emptyPrefix {
+		return g.host, g.prefix
+	}
+	return g.host, g.prefix + "/" + prefix
+}
@@ -0,0 +1,135 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+type groupFilter struct {
+	Filter
+	name string
+	log  *[]string
+}
+
+func (f *groupFilter) PreHandle(request *WebRequest) interface{} {
+	*f.log = append(*f.log, f.name)
+	return true
+}
+
+func (f *groupFilter) PostHandle(request *WebRequest) interface{} {
+	return true
+}
+
+type groupUserCtrl struct {
+	URI `value:"/user"`
+}
+
+func (c *groupUserCtrl) Get(ps struct {
+	URI `value:"/{id}"`
+	Id  string
+}) interface{} {
+	return Content{String: "user " + ps.Id}
+}
+
+type groupPingCtrl struct {
+	URI `value:"/ping"`
+}
+
+func (c *groupPingCtrl) Ping(URI) interface{} {
+	return Content{String: "pong"}
+}
+
+func TestCan_Group(t *testing.T) {
+	var log []string
+	admin := &groupFilter{name: "admin", log: &log}
+	audit := &groupFilter{name: "audit", log: &log}
+	can := NewCan().Route(&groupPingCtrl{})
+	can.Group("/admin", func(g *Group) {
+		g.Route(&groupPingCtrl{})
+		g.Group("/v1", func(g *Group) {
+			g.Tag("v1").Use(audit).Route(&groupUserCtrl{})
+		})
+		// 在注册路由之后调用也对组内的路由生效
+		g.Use(admin).Tag("admin")
+	})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url      string
+		wantBody string
+		wantLog  []string
+	}{
+		{url: "/ping", wantBody: "pong"},
+		{url: "/admin/ping", wantBody: "pong", wantLog: []string{"admin"}},
+		{url: "/admin/v1/user/1", wantBody: "user 1", wantLog: []string{"admin", "audit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			log = nil
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+			if !reflect.DeepEqual(log, tt.wantLog) {
+				t.Errorf("ServeHTTP() filters = %v, want %v", log, tt.wantLog)
+			}
+		})
+	}
+	for _, ri := range can.Routes() {
+		if ri.Path != "/admin/v1/user/{id}" {
+			continue
+		}
+		if !reflect.DeepEqual(ri.Filters, []string{"groupFilter"}) {
+			t.Errorf("Routes() filters = %v", ri.Filters)
+		}
+		if !reflect.DeepEqual(ri.Tags, []string{"admin", "v1"}) {
+			t.Errorf("Routes() tags = %v", ri.Tags)
+		}
+		return
+	}
+	t.Errorf("Routes() missing /admin/v1/user/{id}")
+}
+
+type groupCorsFilter struct {
+	Filter
+}
+
+func (f *groupCorsFilter) PreHandle(request *WebRequest) interface{} {
+	request.ResponseWriter.Header().Set("Access-Control-Allow-Origin", "*")
+	return true
+}
+
+func (f *groupCorsFilter) PostHandle(request *WebRequest) interface{} {
+	return true
+}
+
+func TestCan_Group_preflight(t *testing.T) {
+	can := NewCan()
+	can.Group("/api", func(g *Group) {
+		g.Use(&groupCorsFilter{}).Route(&groupUserCtrl{})
+	})
+	can.build()
+	tests := []struct {
+		method   string
+		url      string
+		wantCode int
+		wantCors string
+	}{
+		{method: http.MethodOptions, url: "/api/user/1", wantCode: http.StatusNoContent, wantCors: "*"},
+		{method: http.MethodGet, url: "/api/user/1", wantCode: http.StatusOK, wantCors: "*"},
+		{method: http.MethodOptions, url: "/other", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.url, nil))
+			if rec.Code != tt.wantCode || rec.Header().Get("Access-Control-Allow-Origin") != tt.wantCors {
+				t.Errorf("ServeHTTP() = %v %v, want %v %v", rec.Code, rec.Header(), tt.wantCode, tt.wantCors)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type hostApiCtrl struct {
+	URI `value:"/user" host:"api.example.com"`
+}
+
+func (c *hostApiCtrl) Get(ps struct {
+	URI `value:"/{id}"`
+	Id  string
+}) interface{} {
+	return Content{String: "api " + ps.Id}
+}
+
+type hostTenantCtrl struct {
+	URI `value:"/user"`
+}
+
+func (c *hostTenantCtrl) Get(ps struct {
+	URI    `value:"/{id}"`
+	Tenant string
+	Id     string
+}) interface{} {
+	return Content{String: ps.Tenant + " " + ps.Id}
+}
+
+func (c *hostTenantCtrl) Admin(ps struct {
+	URI `value:"/admin" host:"admin.example.com"`
+}) interface{} {
+	return Content{String: "admin"}
+}
+
+type hostDefaultCtrl struct {
+	URI `value:"/user"`
+}
+
+func (c *hostDefaultCtrl) Get(ps struct {
+	URI `value:"/{id}"`
+	Id  string
+}) interface{} {
+	return Content{String: "default " + ps.Id}
+}
+
+func TestCan_Host(t *testing.T) {
+	can := NewCan().Route(&hostApiCtrl{}, &hostDefaultCtrl{})
+	can.Host("{tenant}.example.com").Route(&hostTenantCtrl{})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		host     string
+		url      string
+		wantCode int
+		wantBody string
+	}{
+		{host: "api.example.com", url: "/user/1", wantCode: http.StatusOK, wantBody: "api 1"},
+		{host: "API.Example.com:8080", url: "/user/1", wantCode: http.StatusOK, wantBody: "api 1"},
+		{host: "acme.example.com", url: "/user/2", wantCode: http.StatusOK, wantBody: "acme 2"},
+		{host: "localhost", url: "/user/3", wantCode: http.StatusOK, wantBody: "default 3"},
+		{host: "a.b.example.com", url: "/user/4", wantCode: http.StatusOK, wantBody: "default 4"},
+		{host: "admin.example.com", url: "/user/admin", wantCode: http.StatusOK, wantBody: "admin"},
+		{host: "acme.example.com", url: "/user/admin", wantCode: http.StatusOK, wantBody: "acme admin"},
+		{host: "localhost", url: "/none", wantCode: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host+tt.url, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			req.Host = tt.host
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+	got, err := can.URLFor("hostTenantCtrl.Get", "tenant", "acme", "id", 2)
+	if err != nil || got != "//acme.example.com/user/2" {
+		t.Errorf("URLFor() = %v, %v", got, err)
+	}
+}
+
+func Test_hostDispatcher_order(t *testing.T) {
+	cd := newCanMux()
+	for _, h := range []string{"{tenant}.example.com", "*.example.com", "api.example.com", "api-*.example.com"} {
+		cd.host(h)
+	}
+	var got []string
+	for _, hd := range cd.hosts {
+		got = append(got, hd.pattern)
+	}
+	want := []string{"api.example.com", "api-*.example.com", "{tenant}.example.com", "*.example.com"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("hosts = %v, want %v", got, want)
+		}
+	}
+}
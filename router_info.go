@@ -0,0 +1,130 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"bytes"
+	"html/template"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/JessonChan/canlog"
+)
+
+// RouteInfo 一条已经注册的路由
+type RouteInfo struct {
+	// Host 路由限定的host模式，为空时不限定
+	Host    string
+	Path    string
+	Methods []string
+	// Handler 路由名，可以用于URLFor
+	Handler string
+	// Param 处理函数中带有URI的参数类型，只使用cango.URI时为cango.URI，通过Handle注册时为Req
+	Param reflect.Type
+	// Response 通过Handle注册时的返回值类型，其它方式注册时为nil
+	Response reflect.Type
+	// Filters 在这条路由上生效的filter
+	Filters []string
+	// Tags 路由所在分组的标签
+	Tags   []string
+	Source RouteSource
+}
+
+// Routes 返回所有已经构建的路由，路由构建之后（Run或者Validate）才可以使用
+func (can *Can) Routes() []RouteInfo {
+	var ris []RouteInfo
+	t := can.loadTable()
+	for _, rr := range t.routes {
+		filters := map[string]bool{}
+		for _, m := range rr.methods {
+			for _, f := range t.routeFilters(rr.host, rr.path, m) {
+				filters[f] = true
+			}
+		}
+		ri := RouteInfo{
+			Host:     rr.host,
+			Path:     rr.path,
+			Methods:  append([]string{}, rr.methods...),
+			Handler:  rr.name,
+			Param:    rr.param,
+			Response: rr.resp,
+			Tags:     rr.tags,
+			Source:   rr.source,
+		}
+		for f := range filters {
+			ri.Filters = append(ri.Filters, f)
+		}
+		sort.Strings(ri.Filters)
+		ris = append(ris, ri)
+	}
+	return ris
+}
+
+// DebugRoutes 开启 /_cango/routes 页面，以HTML或者JSON（?format=json）的形式展示路由表
+// 这个页面和普通路由一样会经过filter，可以使用filter做权限控制
+func (can *Can) DebugRoutes() *Can {
+	can.register(func() {
+		can.debugRoutes = true
+	})
+	return can
+}
+
+type routeView struct {
+	Host     string
+	Path     string
+	Methods  []string
+	Handler  string
+	Param    string
+	Response string
+	Filters  []string
+	Source   RouteSource
+}
+
+var routesTpl = template.Must(template.New("routes").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"><title>cango routes</title></head>
+<body>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>Methods</th><th>Host</th><th>Path</th><th>Handler</th><th>Param</th><th>Response</th><th>Filters</th><th>Source</th></tr>
+{{range .}}<tr><td>{{join .Methods ","}}</td><td>{{.Host}}</td><td>{{.Path}}</td><td>{{.Handler}}</td><td>{{.Param}}</td><td>{{.Response}}</td><td>{{join .Filters ","}}</td><td>{{.Source}}</td></tr>
+{{end}}</table>
+</body>
+</html>`))
+
+func (can *Can) routesEndpoint(ps struct {
+	URI    `value:"/_cango/routes"`
+	Format string
+}) interface{} {
+	var views []routeView
+	for _, ri := range can.Routes() {
+		rv := routeView{Host: ri.Host, Path: ri.Path, Methods: ri.Methods, Handler: ri.Handler, Filters: ri.Filters, Source: ri.Source}
+		if ri.Param != nil {
+			rv.Param = ri.Param.String()
+		}
+		if ri.Response != nil {
+			rv.Response = ri.Response.String()
+		}
+		views = append(views, rv)
+	}
+	if ps.Format == "json" || strings.Contains(ps.Request().Request.Header.Get("Accept"), mimeJSON) {
+		return views
+	}
+	bb := &bytes.Buffer{}
+	if err := routesTpl.Execute(bb, views); err != nil {
+		canlog.CanError(err)
+	}
+	return Content{String: bb.String()}
+}
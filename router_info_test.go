@@ -0,0 +1,83 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type infoFilter struct {
+	Filter `value:"/user/*"`
+}
+
+func (f *infoFilter) PreHandle(request *WebRequest) interface{} {
+	return true
+}
+
+func (f *infoFilter) PostHandle(request *WebRequest) interface{} {
+	return true
+}
+
+func TestCan_Routes(t *testing.T) {
+	can := NewCan().Route(&checkUserCtrl{}).Filter(&infoFilter{})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	var got *RouteInfo
+	for _, ri := range can.Routes() {
+		if ri.Path == "/user/{id}" {
+			ri := ri
+			got = &ri
+		}
+	}
+	if got == nil {
+		t.Fatalf("Routes() missing /user/{id}")
+	}
+	if got.Handler != "github.com/JessonChan/cango.checkUserCtrl.Get" {
+		t.Errorf("Routes() handler = %v", got.Handler)
+	}
+	if !reflect.DeepEqual(got.Methods, []string{http.MethodGet}) {
+		t.Errorf("Routes() methods = %v", got.Methods)
+	}
+	if got.Param == nil || got.Param.Kind() != reflect.Struct {
+		t.Errorf("Routes() param = %v", got.Param)
+	}
+	if !reflect.DeepEqual(got.Filters, []string{"infoFilter"}) {
+		t.Errorf("Routes() filters = %v", got.Filters)
+	}
+	if got.Source != RouteSourceController {
+		t.Errorf("Routes() source = %v", got.Source)
+	}
+}
+
+func TestCan_DebugRoutes(t *testing.T) {
+	can := NewCan().Route(&checkUserCtrl{}).DebugRoutes()
+	can.build()
+	tests := []struct {
+		url      string
+		wantType string
+		wantBody string
+	}{
+		{url: "/_cango/routes", wantType: "text/html", wantBody: "<td>/user/{id}</td>"},
+		{url: "/_cango/routes?format=json", wantBody: `"Path":"/user/{id}"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("ServeHTTP() body = %v, want contains %v", rec.Body.String(), tt.wantBody)
+			}
+			if !strings.HasPrefix(rec.Header().Get("Content-Type"), tt.wantType) {
+				t.Errorf("ServeHTTP() content type = %v", rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+	rec := httptest.NewRecorder()
+	NewCan().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/_cango/routes", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("routes page should be opt-in, code = %v", rec.Code)
+	}
+}
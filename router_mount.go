@@ -0,0 +1,67 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// mountVar 挂载路由使用的catch-all变量
+const mountVar = "{mount...}"
+
+// Mount 把http.Handler挂载到prefix下，prefix以及它下面的所有路径、所有方法都交给h处理
+// 可以挂载pprof、http.ServeMux或者另外一个*Can，匹配前缀的filter会在h之前执行
+// strip 为true时去掉请求路径中的prefix再交给h，挂载*Can时一般需要去掉
+// 比prefix/{mount...}更具体的路由依然优先匹配
+func (can *Can) Mount(prefix string, h http.Handler, strip ...bool) *Can {
+	can.register(func() {
+		can.mount(nil, prefix, h, append(strip, false)[0])
+	})
+	return can
+}
+
+// Mount 把http.Handler挂载到分组前缀之后的prefix下，组内的filter同样生效
+func (g *Group) Mount(prefix string, h http.Handler, strip ...bool) *Group {
+	g.can.register(func() {
+		g.can.mount(g, prefix, h, append(strip, false)[0])
+	})
+	return g
+}
+
+func (can *Can) mount(g *Group, prefix string, h http.Handler, strip bool) {
+	host, prefix := g.scope(prefix)
+	prefix = filepath.Clean("/" + prefix)
+	handler := h
+	if strip && prefix != "/" {
+		handler = http.StripPrefix(prefix, h)
+	}
+	can.routeMux.ctrlEntryMap[host+prefix+mountVar] = ctrlEntry{group: g, host: host, prefix: prefix, kind: reflect.Interface, ctrl: h, handler: handler, source: RouteSourceMount, tim: time.Now().Unix(), seq: nextCtrlEntrySeq()}
+}
+
+// routeHandler 注册挂载的handler，挂载的*Can和当前的一起构建
+func (can *Can) routeHandler(t *routeTable, ce ctrlEntry) {
+	if sub, ok := ce.ctrl.(*Can); ok {
+		sub.build()
+	}
+	path := filepath.Clean(ce.prefix + "/" + mountVar)
+	name := "Mount." + ce.prefix
+	mux := t.dispatcher.(*canDispatcher).host(ce.host)
+	invoker := &Invoker{kind: invokeByHandler, handler: ce.handler}
+	mux.NewForwarder(name, invoker).PathMethods(path, allHTTPMethods...)
+	t.routes = append(t.routes, &routeRecord{name: name, path: path, methods: allHTTPMethods, source: ce.source, host: ce.host, group: ce.group, tags: ce.group.chainTags(), invoker: invoker})
+}
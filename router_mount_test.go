@@ -0,0 +1,76 @@
+package cango
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type mountFilter struct {
+	Filter `value:"/mux/*"`
+	count  *int
+}
+
+func (f *mountFilter) PreHandle(request *WebRequest) interface{} {
+	*f.count++
+	return true
+}
+
+func (f *mountFilter) PostHandle(request *WebRequest) interface{} {
+	return true
+}
+
+func TestCan_Mount(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := io.ReadAll(r.Body)
+		_, _ = w.Write([]byte(r.Method + " " + r.URL.Path + " " + string(bs)))
+	})
+	sub := NewCan().Route(&groupPingCtrl{})
+	count := 0
+	can := NewCan().Route(&groupPingCtrl{}).
+		Mount("/mux", mux, true).
+		Mount("/raw", mux).
+		Mount("/sub", sub, true).
+		Filter(&mountFilter{count: &count})
+	can.Group("/api", func(g *Group) {
+		g.Mount("/mux", mux, true)
+	})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method    string
+		url       string
+		body      string
+		wantCode  int
+		wantBody  string
+		wantCount int
+	}{
+		{method: http.MethodPost, url: "/mux/echo", body: "a=1", wantCode: http.StatusOK, wantBody: "POST /echo a=1", wantCount: 1},
+		{method: http.MethodGet, url: "/api/mux/echo", wantCode: http.StatusOK, wantBody: "GET /echo "},
+		{method: http.MethodGet, url: "/raw/echo", wantCode: http.StatusNotFound},
+		{method: http.MethodGet, url: "/sub/ping", wantCode: http.StatusOK, wantBody: "pong"},
+		{method: http.MethodGet, url: "/ping", wantCode: http.StatusOK, wantBody: "pong"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.url, func(t *testing.T) {
+			count = 0
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+			if count != tt.wantCount {
+				t.Errorf("ServeHTTP() filter count = %v, want %v", count, tt.wantCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"path/filepath"
+	"reflect"
+)
+
+// 运行中（Run之后）增删路由和filter时，会根据注册信息构建一个新的路由表并原子替换
+// 正在处理的请求继续使用旧的路由表，之后的请求使用新的路由表
+// 新的路由表有冲突或者依赖无法注入时不会生效，错误通过Err获取
+
+// RemoveRoute 移除结构体的所有路由，包括在分组中注册的和通过RegisterURI注册的
+func (can *Can) RemoveRoute(uris ...URI) *Can {
+	can.register(func() {
+		for _, uri := range uris {
+			typ := toPtrKind(uri)
+			can.removedURIs[typ] = true
+			for key, ce := range can.routeMux.ctrlEntryMap {
+				if ce.kind == reflect.Ptr && toPtrKind(ce.ctrl) == typ {
+					delete(can.routeMux.ctrlEntryMap, key)
+				}
+			}
+		}
+	})
+	return can
+}
+
+// RemoveRouteFunc 移除通过RouteFunc或者Handle注册的函数
+func (can *Can) RemoveRouteFunc(fns ...interface{}) *Can {
+	can.register(func() {
+		for _, fn := range fns {
+			fv := reflect.ValueOf(fn)
+			if fv.Kind() != reflect.Func {
+				continue
+			}
+			for key, ce := range can.routeMux.ctrlEntryMap {
+				if ce.kind != reflect.Func {
+					continue
+				}
+				if ce.fn.Func.Pointer() == fv.Pointer() || ce.resp != nil && reflect.ValueOf(ce.ctrl).Pointer() == fv.Pointer() {
+					delete(can.routeMux.ctrlEntryMap, key)
+				}
+			}
+		}
+	})
+	return can
+}
+
+// Unmount 移除prefix上通过Mount挂载的handler
+func (can *Can) Unmount(prefix string) *Can {
+	prefix = filepath.Clean("/" + prefix)
+	can.register(func() {
+		for key, ce := range can.routeMux.ctrlEntryMap {
+			if ce.source == RouteSourceMount && ce.prefix == prefix {
+				delete(can.routeMux.ctrlEntryMap, key)
+			}
+		}
+	})
+	return can
+}
+
+// RemoveFilter 移除filter，包括通过RegisterFilter注册的和分组中使用的同类型filter
+// 只是在路由表中不再使用，注册信息和分组都不会被修改，再次调用Filter时恢复
+func (can *Can) RemoveFilter(filters ...Filter) *Can {
+	can.register(func() {
+		for _, f := range filters {
+			can.removedFilters[reflect.TypeOf(f)] = true
+		}
+	})
+	return can
+}
@@ -0,0 +1,284 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCan_runtimeRoute(t *testing.T) {
+	count := 0
+	filter := &mountFilter{count: &count}
+	can := NewCan().Route(&groupPingCtrl{})
+	can.build()
+	get := func(url string) int {
+		rec := httptest.NewRecorder()
+		can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec.Code
+	}
+	tests := []struct {
+		name      string
+		change    func()
+		url       string
+		wantCode  int
+		wantCount int
+	}{
+		{name: "before", change: func() {}, url: "/user/1", wantCode: http.StatusNotFound},
+		{name: "route", change: func() { can.Route(&groupUserCtrl{}) }, url: "/user/1", wantCode: http.StatusOK},
+		{name: "mount", change: func() { can.Mount("/mux", http.NotFoundHandler()).Filter(filter) }, url: "/mux/a", wantCode: http.StatusNotFound, wantCount: 1},
+		{name: "remove filter", change: func() { can.RemoveFilter(filter) }, url: "/mux/a", wantCode: http.StatusNotFound},
+		{name: "unmount", change: func() { can.Unmount("/mux") }, url: "/mux/a", wantCode: http.StatusNotFound},
+		{name: "remove", change: func() { can.RemoveRoute(&groupUserCtrl{}) }, url: "/user/1", wantCode: http.StatusNotFound},
+		{name: "keep", change: func() {}, url: "/ping", wantCode: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count = 0
+			tt.change()
+			if got := get(tt.url); got != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", got, tt.wantCode)
+			}
+			if count != tt.wantCount {
+				t.Errorf("ServeHTTP() filter count = %v, want %v", count, tt.wantCount)
+			}
+		})
+	}
+	if len(can.Routes()) != 1 {
+		t.Errorf("Routes() = %v", can.Routes())
+	}
+}
+
+func TestCan_runtimeRouteConcurrent(t *testing.T) {
+	can := NewCan().Route(&groupPingCtrl{})
+	can.build()
+	wg := sync.WaitGroup{}
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				rec := httptest.NewRecorder()
+				can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+				if rec.Code != http.StatusOK {
+					t.Errorf("ServeHTTP() code = %v", rec.Code)
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 50; i++ {
+		can.Route(&groupUserCtrl{})
+		can.RemoveRoute(&groupUserCtrl{})
+	}
+	close(stop)
+	wg.Wait()
+}
+
+type runtimeDupCtrl struct {
+	URI `value:"/ping"`
+}
+
+func (c *runtimeDupCtrl) Pong(URI) interface{} {
+	return Content{String: "dup"}
+}
+
+type runtimeBadRegCtrl struct {
+	URI `value:"/user/{id:[}"`
+}
+
+func (c *runtimeBadRegCtrl) Get(URI) interface{} {
+	return Content{String: "bad"}
+}
+
+type runtimeBadCatchAllCtrl struct {
+	URI `value:"/files/{path...}/raw"`
+}
+
+func (c *runtimeBadCatchAllCtrl) Get(URI) interface{} {
+	return Content{String: "bad"}
+}
+
+func TestCan_runtimeInvalidPattern(t *testing.T) {
+	can := NewCan().Route(&groupPingCtrl{})
+	can.build()
+	tests := []struct {
+		name string
+		add  func() error
+	}{
+		{name: "constraint", add: func() error { return can.Route(&runtimeBadRegCtrl{}).Err() }},
+		{name: "catchAll", add: func() error { return can.Route(&runtimeBadCatchAllCtrl{}).Err() }},
+		{name: "host", add: func() error { can.Host("{sub:[}.example.com").Route(&runtimeDupCtrl{}); return can.Err() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 不能解析的pattern返回错误，服务继续使用原来的路由表
+			if err := tt.add(); err == nil || !strings.Contains(err.Error(), "invalid route pattern") {
+				t.Fatalf("Err() = %v, want invalid route pattern", err)
+			}
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ping", nil))
+			if rec.Body.String() != "pong" {
+				t.Errorf("ServeHTTP() = %v, want pong", rec.Body.String())
+			}
+			if len(can.Routes()) != 1 {
+				t.Errorf("Routes() = %v", can.Routes())
+			}
+		})
+	}
+	if err := NewCan().Route(&runtimeBadRegCtrl{}).Validate(); err == nil {
+		t.Error("Validate() = nil, want invalid route pattern")
+	}
+}
+
+type runtimeRegCtrl struct {
+	URI `value:"/reg"`
+}
+
+func (c *runtimeRegCtrl) Reg(URI) interface{} {
+	return Content{String: "reg"}
+}
+
+func TestCan_runtimeRegisterURIOrder(t *testing.T) {
+	uri := &runtimeRegCtrl{}
+	RegisterURI(uri, "runtimeReg")
+	defer delete(uriRegMap, uri)
+	can := NewCan("runtimeReg")
+	can.build()
+	can.Route(&groupPingCtrl{})
+	can.Route(&groupUserCtrl{})
+	// 重新构建时RegisterURI注册的结构体保持在最前面
+	routes := can.Routes()
+	if len(routes) != 3 || routes[0].Path != "/reg" {
+		t.Errorf("Routes() = %v", routes)
+	}
+}
+
+func TestCan_runtimeRouteRejected(t *testing.T) {
+	can := NewCan().Route(&groupPingCtrl{})
+	can.build()
+	get := func(url string) string {
+		rec := httptest.NewRecorder()
+		can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, url, nil))
+		return rec.Body.String()
+	}
+	// 冲突的修改不生效，继续使用原来的路由表
+	if can.Route(&runtimeDupCtrl{}).Err() == nil {
+		t.Fatal("Err() = nil, want route conflict")
+	}
+	if got := get("/ping"); got != "pong" {
+		t.Errorf("ServeHTTP() = %v, want pong", got)
+	}
+	// 冲突的注册被撤销，之后的修改可以生效
+	if err := can.Route(&groupUserCtrl{}).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(can.Routes()) != 2 {
+		t.Errorf("Routes() = %v", can.Routes())
+	}
+	if err := can.Service(1).Route(&injectMissingCtrl{}).Err(); err == nil {
+		t.Error("Err() = nil, want inject error")
+	}
+	if len(can.Routes()) != 2 {
+		t.Errorf("Routes() = %v", can.Routes())
+	}
+}
+
+func TestCan_runtimeRemoveGroupFilter(t *testing.T) {
+	count := 0
+	filter := &mountFilter{count: &count}
+	can := NewCan()
+	var group *Group
+	can.Group("/g", func(g *Group) {
+		group = g
+		g.Use(filter).Route(&groupPingCtrl{})
+	})
+	can.build()
+	tests := []struct {
+		name      string
+		change    func()
+		wantCount int
+	}{
+		{name: "use", change: func() {}, wantCount: 1},
+		{name: "remove", change: func() { can.RemoveFilter(filter) }, wantCount: 0},
+		{name: "restore", change: func() { can.Filter(filter) }, wantCount: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count = 0
+			tt.change()
+			can.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/g/ping", nil))
+			if count != tt.wantCount {
+				t.Errorf("ServeHTTP() filter count = %v, want %v", count, tt.wantCount)
+			}
+			if len(group.filters) != 1 {
+				t.Errorf("Group.filters = %v", group.filters)
+			}
+		})
+	}
+}
+
+func TestCan_runtimeStaticWalkedOnce(t *testing.T) {
+	root := t.TempDir()
+	static := filepath.Join(root, "static")
+	_ = os.MkdirAll(static, 0o755)
+	_ = os.WriteFile(filepath.Join(static, "a.txt"), []byte("a"), 0o644)
+	can := NewCan()
+	can.rootPath, can.staticRootPath = root, static
+	can.build()
+	// 运行中的修改不再遍历静态文件目录
+	_ = os.WriteFile(filepath.Join(static, "b.txt"), []byte("b"), 0o644)
+	can.Route(&groupPingCtrl{})
+	paths := map[string]bool{}
+	for _, ri := range can.Routes() {
+		paths[ri.Path] = true
+	}
+	if !paths["/static/a.txt"] || paths["/static/b.txt"] || !paths["/ping"] {
+		t.Errorf("Routes() = %v", paths)
+	}
+}
+
+func TestCan_runtimeRejectedRestoresAll(t *testing.T) {
+	count := 0
+	filter := &mountFilter{count: &count}
+	can := NewCan().Route(&conventionUserCtrl{}).RouteFunc(func(ps struct {
+		URI `value:"/convention-user/profile"`
+	}) interface{} {
+		return nil
+	})
+	var group *Group
+	can.Group("/g", func(g *Group) {
+		group = g
+		g.Tag("v1").Route(&groupPingCtrl{})
+	})
+	can.build()
+	// 约定路由生成的路径和已有的路由冲突，修改被撤销
+	if can.RouteConvention(NamingKebab).Err() == nil {
+		t.Fatal("Err() = nil, want route conflict")
+	}
+	if can.routeNaming != NamingNone {
+		t.Errorf("routeNaming = %v, want NamingNone", can.routeNaming)
+	}
+	// 撤销分组、filter和Provider上的所有修改
+	snap := can.snapshot()
+	group.Use(filter).Tag("v2")
+	can.Filter(filter, &groupPingCtrl{}).RemoveFilter(&groupFilter{}).RouteConvention(NamingSnake)
+	can.Provide(func(r *WebRequest) (*providerUser, error) { return nil, nil })
+	can.restore(snap)
+	if len(group.filters) != 0 || !reflect.DeepEqual(group.tags, []string{"v1"}) {
+		t.Errorf("group = %v %v", group.filters, group.tags)
+	}
+	if len(can.filterDispatcher) != 0 || len(can.removedFilters) != 0 || len(can.providers) != 0 || can.routeNaming != NamingNone {
+		t.Errorf("registration = %v %v %v %v", can.filterDispatcher, can.removedFilters, can.providers, can.routeNaming)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"context"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Handle 注册类型化的处理函数，Req使用和结构体路由相同的方式解析（form、path变量、header、cookie、json）
+// Resp的类型会记录在 RouteInfo.Response 中，返回的error和 (T, error) 形式的处理函数一样处理
+// method为空时为GET，在运行中调用时会立即生效
+func Handle[Req, Resp any](can *Can, path, method string, fn func(ctx context.Context, req Req) (Resp, error)) *Can {
+	if method == "" {
+		method = http.MethodGet
+	}
+	method = strings.ToUpper(method)
+	adapter := func(uri URI, req Req) (Resp, error) {
+		return fn(uri.Request().Request.Context(), req)
+	}
+	fv := reflect.ValueOf(adapter)
+	funcMethod := reflect.Method{
+		Name: runtime.FuncForPC(reflect.ValueOf(fn).Pointer()).Name(),
+		Type: fv.Type(),
+		Func: fv,
+	}
+	can.register(func() {
+		path := filepath.Clean("/" + path)
+		can.routeMux.ctrlEntryMap[method+" "+path+funcMethod.Name] = ctrlEntry{
+			prefix:  path,
+			kind:    reflect.Func,
+			ctrl:    fn,
+			fn:      funcMethod,
+			methods: []string{method},
+			resp:    reflect.TypeOf((*Resp)(nil)).Elem(),
+			source:  RouteSourceFunc,
+			tim:     time.Now().Unix(),
+			seq:     nextCtrlEntrySeq(),
+		}
+	})
+	return can
+}
+
+// routeTyped 注册通过Handle注册的处理函数，路径和方法已经确定，不需要解析URI标签
+func (can *Can) routeTyped(t *routeTable, ce ctrlEntry) {
+	name := "Handle." + ce.fn.Name
+	mux := t.dispatcher.(*canDispatcher).host(ce.host)
+	invoker := &Invoker{kind: invokeBySelf, Method: &ce.fn, plan: bindPlanOf(ce.fn.Type), providers: can.argProviders(ce.group, name, ce.fn.Type, invokeBySelf)}
+	mux.NewForwarder(name, invoker).PathMethods(ce.prefix, ce.methods...)
+	t.routes = append(t.routes, &routeRecord{name: name, path: ce.prefix, methods: ce.methods, source: ce.source, param: ce.fn.Type.In(1), resp: ce.resp, host: ce.host, group: ce.group, tags: ce.group.chainTags(), invoker: invoker})
+}
@@ -0,0 +1,84 @@
+package cango
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type typedUserReq struct {
+	Id   int
+	Name string `json:"name"`
+}
+
+type typedUserResp struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+func getTypedUser(ctx context.Context, req typedUserReq) (typedUserResp, error) {
+	if req.Id == 0 {
+		return typedUserResp{}, NewHTTPError(http.StatusNotFound, "no user")
+	}
+	return typedUserResp{Id: req.Id, Name: req.Name}, nil
+}
+
+func TestHandle(t *testing.T) {
+	can := NewCan()
+	Handle(can, "/typed/user/{id}", "", getTypedUser)
+	Handle(can, "/typed/user", http.MethodPost, func(ctx context.Context, req *typedUserReq) (*typedUserResp, error) {
+		if ctx == nil {
+			t.Error("Handle() ctx is nil")
+		}
+		return &typedUserResp{Id: 1, Name: req.Name}, nil
+	})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		method   string
+		url      string
+		body     string
+		wantCode int
+		wantBody string
+	}{
+		{method: http.MethodGet, url: "/typed/user/7?name=cango", wantCode: http.StatusOK, wantBody: `{"id":7,"name":"cango"}`},
+		{method: http.MethodGet, url: "/typed/user/0", wantCode: http.StatusNotFound, wantBody: `{"detail":"no user","instance":"/typed/user/0","status":404,"title":"Not Found"}`},
+		{method: http.MethodPost, url: "/typed/user", body: `{"name":"json"}`, wantCode: http.StatusOK, wantBody: `{"id":1,"name":"json"}`},
+		{method: http.MethodGet, url: "/typed/user", wantCode: http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+tt.url, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", mimeJSON)
+			}
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, req)
+			if rec.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() code = %v, want %v", rec.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+
+	resp := map[string]reflect.Type{}
+	for _, ri := range can.Routes() {
+		resp[ri.Path] = ri.Response
+	}
+	if resp["/typed/user/{id}"] != reflect.TypeOf(typedUserResp{}) || resp["/typed/user"] != reflect.TypeOf(&typedUserResp{}) {
+		t.Errorf("Routes() Response = %v", resp)
+	}
+
+	can.RemoveRouteFunc(getTypedUser)
+	rec := httptest.NewRecorder()
+	can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/typed/user/7", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("RemoveRouteFunc() code = %v, want %v", rec.Code, http.StatusNotFound)
+	}
+}
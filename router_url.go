@@ -0,0 +1,176 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// URLFor 根据处理函数的路由名生成url，路由构建之后（Run或者Validate）才可以使用
+// name 为路由名，如 UserCtrl.Profile，也可以是完整的 github.com/xx/ctrl.UserCtrl.Profile
+// params 为成对的 key,value，或者一个 map[string]interface{}/map[string]string/url.Values
+// params 中的值优先填充路径中的{var}，剩余的做为query参数
+// {var} 的值不能为空，也不能含有/和.，否则生成的地址无法匹配回同一个路由；{path...} 不受限制
+// 模板中可以使用 {{urlfor "UserCtrl.Profile" "id" .Id}}
+// 限定了host的路由同时填充host中的变量，返回 //host/path 形式的地址
+func (can *Can) URLFor(name string, params ...interface{}) (string, error) {
+	values, err := urlParams(params)
+	if err != nil {
+		return "", err
+	}
+	rrs, err := can.lookupRouteName(name)
+	if err != nil {
+		return "", err
+	}
+	// 一个处理函数可以有多个路径，使用第一个变量都能满足的
+	for i, rr := range rrs {
+		path, used, err := fillPath(rr.path, values)
+		if err == nil && rr.host != "" {
+			var host string
+			var hostUsed map[string]bool
+			host, hostUsed, err = fillPath(rr.host, values)
+			for k := range hostUsed {
+				used[k] = true
+			}
+			path = "//" + host + path
+		}
+		if err != nil {
+			if i == len(rrs)-1 {
+				return "", fmt.Errorf("cango urlfor %s: %w", name, err)
+			}
+			continue
+		}
+		query := url.Values{}
+		for k, vs := range values {
+			if !used[k] {
+				query[k] = vs
+			}
+		}
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+		return path, nil
+	}
+	return "", errors.New("cango urlfor can't find the route " + name)
+}
+
+// lookupRouteName 按路由名查找，支持完整匹配或者以.分隔的后缀匹配
+func (can *Can) lookupRouteName(name string) ([]*routeRecord, error) {
+	var exact, suffix []*routeRecord
+	names := map[string]bool{}
+	for _, rr := range can.loadTable().routes {
+		if rr.name == name {
+			exact = append(exact, rr)
+			continue
+		}
+		if strings.HasSuffix(rr.name, "."+name) {
+			suffix = append(suffix, rr)
+			names[rr.name] = true
+		}
+	}
+	if len(exact) > 0 {
+		return exact, nil
+	}
+	if len(names) > 1 {
+		var ns []string
+		for n := range names {
+			ns = append(ns, n)
+		}
+		sort.Strings(ns)
+		return nil, fmt.Errorf("cango urlfor %s is ambiguous: %s", name, strings.Join(ns, ", "))
+	}
+	if len(suffix) == 0 {
+		return nil, errors.New("cango urlfor can't find the route " + name)
+	}
+	return suffix, nil
+}
+
+func urlParams(params []interface{}) (url.Values, error) {
+	values := url.Values{}
+	if len(params) == 1 {
+		switch ps := params[0].(type) {
+		case url.Values:
+			for k, vs := range ps {
+				values[k] = append([]string{}, vs...)
+			}
+			return values, nil
+		case map[string]string:
+			for k, v := range ps {
+				values.Set(k, v)
+			}
+			return values, nil
+		case map[string]interface{}:
+			for k, v := range ps {
+				values.Set(k, fmt.Sprint(v))
+			}
+			return values, nil
+		}
+	}
+	if len(params)%2 != 0 {
+		return nil, errors.New("cango urlfor params must be key-value pairs")
+	}
+	for i := 0; i < len(params); i += 2 {
+		key, ok := params[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("cango urlfor param key %v must be string", params[i])
+		}
+		values.Add(key, fmt.Sprint(params[i+1]))
+	}
+	return values, nil
+}
+
+// fillPath 使用values填充pattern中的路径变量，返回填充后的路径和被使用的key
+func fillPath(pattern string, values url.Values) (string, map[string]bool, error) {
+	// 去掉变量之后还有*的是通配符，无法反向生成
+	if skeleton, _ := replaceVars(pattern, func(word) (string, error) { return "", nil }); strings.Contains(skeleton, "*") {
+		return "", nil, errors.New("wildcard pattern " + pattern + " can't be reversed")
+	}
+	used := map[string]bool{}
+	path, err := replaceVars(pattern, func(w word) (string, error) {
+		vs := values[w.key]
+		if len(vs) == 0 {
+			return "", errors.New("missing path variable " + w.key + " for " + pattern)
+		}
+		v := vs[0]
+		if w.check != nil && !w.check(v) {
+			return "", fmt.Errorf("path variable %s=%s does not satisfy %s", w.key, v, w.constraint)
+		}
+		used[w.key] = true
+		if !w.isCatchAll {
+			// 路由按/和.切分，含有它们的值即使转义了也无法匹配回同一个路由
+			if v == "" || strings.ContainsAny(v, "/.") {
+				return "", fmt.Errorf("path variable %s=%q must be a single segment without '/' or '.'", w.key, v)
+			}
+			return url.PathEscape(v), nil
+		}
+		segments := strings.Split(v, "/")
+		for k, seg := range segments {
+			segments[k] = url.PathEscape(seg)
+		}
+		return strings.Join(segments, "/"), nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	// {path...} 为空时会留下结尾的/
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+	return path, used, nil
+}
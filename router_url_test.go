@@ -0,0 +1,143 @@
+package cango
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+type urlUserCtrl struct {
+	URI `value:"/user"`
+}
+
+func (c *urlUserCtrl) Profile(ps struct {
+	URI `value:"/{id:int}/profile"`
+}) interface{} {
+	return nil
+}
+
+func (c *urlUserCtrl) Files(ps struct {
+	URI `value:"/{id}/files/{path...}"`
+}) interface{} {
+	return nil
+}
+
+func (c *urlUserCtrl) Static(ps struct {
+	URI `value:"/static/*"`
+}) interface{} {
+	return nil
+}
+
+func (c *urlUserCtrl) List(URI) interface{} {
+	return nil
+}
+
+func TestCan_URLFor(t *testing.T) {
+	can := NewCan().Route(&urlUserCtrl{})
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		route   string
+		params  []interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "pairs", route: "urlUserCtrl.Profile", params: []interface{}{"id", 12}, want: "/user/12/profile"},
+		{name: "full_name", route: "github.com/JessonChan/cango.urlUserCtrl.Profile", params: []interface{}{"id", 12}, want: "/user/12/profile"},
+		{name: "query", route: "urlUserCtrl.Profile", params: []interface{}{"id", 12, "tab", "a b"}, want: "/user/12/profile?tab=a+b"},
+		{name: "map", route: "urlUserCtrl.Profile", params: []interface{}{map[string]interface{}{"id": 1}}, want: "/user/1/profile"},
+		{name: "values", route: "urlUserCtrl.List", params: []interface{}{url.Values{"page": {"2"}}}, want: "/user?page=2"},
+		{name: "catch_all", route: "urlUserCtrl.Files", params: []interface{}{"id", "u 1", "path", "a/b c.txt"}, want: "/user/u%201/files/a/b%20c.txt"},
+		{name: "missing", route: "urlUserCtrl.Profile", wantErr: true},
+		{name: "dot", route: "urlUserCtrl.Files", params: []interface{}{"id", "a.b", "path", "c"}, wantErr: true},
+		{name: "slash", route: "urlUserCtrl.Files", params: []interface{}{"id", "a/b", "path", "c"}, wantErr: true},
+		{name: "constraint", route: "urlUserCtrl.Profile", params: []interface{}{"id", "abc"}, wantErr: true},
+		{name: "wildcard", route: "urlUserCtrl.Static", wantErr: true},
+		{name: "not_found", route: "urlUserCtrl.None", wantErr: true},
+		{name: "odd_params", route: "urlUserCtrl.Profile", params: []interface{}{"id"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := can.URLFor(tt.route, tt.params...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("URLFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("URLFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type urlEchoCtrl struct {
+	URI `value:"/echo"`
+}
+
+func (c *urlEchoCtrl) Get(ps struct {
+	URI `value:"/{id}"`
+	Id  string
+}) interface{} {
+	return Content{String: ps.Id}
+}
+
+func (c *urlEchoCtrl) Files(ps struct {
+	URI  `value:"/{id}/files/{path...}"`
+	Id   string
+	Path string
+}) interface{} {
+	return Content{String: ps.Id + ":" + ps.Path}
+}
+
+func TestCan_URLFor_roundTrip(t *testing.T) {
+	can := NewCan().Route(&urlEchoCtrl{})
+	can.build()
+	tests := []struct {
+		route    string
+		params   []interface{}
+		wantBody string
+		wantErr  bool
+	}{
+		{route: "urlEchoCtrl.Get", params: []interface{}{"id", "u 1"}, wantBody: "u 1"},
+		{route: "urlEchoCtrl.Get", params: []interface{}{"id", "a.json"}, wantErr: true},
+		{route: "urlEchoCtrl.Files", params: []interface{}{"id", "u", "path", "a/b.c.txt"}, wantBody: "u:a/b.c.txt"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.params...), func(t *testing.T) {
+			u, err := can.URLFor(tt.route, tt.params...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("URLFor() = %v %v, wantErr %v", u, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, u, nil))
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP(%v) = %v, want %v", u, rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCan_URLFor_template(t *testing.T) {
+	can := NewCan().Route(&urlUserCtrl{})
+	can.build()
+	tpl := template.Must(template.New("").Funcs(can.tplFuncMap).Parse(`<a href="{{urlfor "urlUserCtrl.Profile" "id" .}}">`))
+	bb := &bytes.Buffer{}
+	if err := tpl.Execute(bb, 7); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(bb.String(), `href="/user/7/profile"`) {
+		t.Errorf("template = %v", bb.String())
+	}
+	if err := tpl.Execute(&bytes.Buffer{}, "x"); err == nil {
+		t.Errorf("template with invalid var should fail")
+	}
+}
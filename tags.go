@@ -15,6 +15,7 @@ package cango
 
 const (
 	uriTagName       string = "value"
+	hostTagName      string = "host"
 	pathFormName     string = "name"
 	cookieTagName    string = "cookie"
 	headerTagName    string = "header"
@@ -22,4 +23,7 @@ const (
 	formValueTagName string = "form"
 	sessionTagName   string = "session"
 	nameTagName      string = "name"
+	injectTagName    string = "inject"
+	timeoutTagName   string = "timeout"
+	tplTagName       string = "tpl"
 )
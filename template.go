@@ -15,10 +15,12 @@
 package cango
 
 import (
+	"errors"
 	"html/template"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 
@@ -96,3 +98,101 @@ func (can *Can) lookupTpl(name string) *template.Template {
 	}
 	return rootTpl.Lookup(name)
 }
+
+// TplResolver ModelView没有指定Tpl时的模板查找方式
+// ctrl 为去掉Ctrl/Controller后缀的结构体名，method 为方法名，返回不带后缀的模板名
+type TplResolver func(ctrl, method string) string
+
+// TplByPath 返回 ctrl/method 形式的模板名，方法名开头的Get/Post等会被去掉
+// 如 TplByPath(NamingSnake) 时 UserCtrl.Profile -> user/profile，UserCtrl.GetOrderList -> user/order_list
+func TplByPath(naming RouteNaming) TplResolver {
+	return func(ctrl, method string) string {
+		words := splitName(method)
+		if _, ok := verbPrefixes[words[0]]; ok && len(words) > 1 {
+			words = words[1:]
+		}
+		return convertName(splitName(ctrl), naming) + "/" + convertName(words, naming)
+	}
+}
+
+var defaultTplResolver = TplByPath(NamingSnake)
+
+// SetTplResolver 设置ModelView没有指定Tpl时的模板查找方式，默认为 TplByPath(NamingSnake)
+// 模板名会依次加上模板后缀（默认为.tpl和.html）在模板文件夹中查找
+func (can *Can) SetTplResolver(resolver TplResolver) *Can {
+	can.tplResolver = resolver
+	return can
+}
+
+// impliedTpl 根据处理函数查找模板，返回找到的模板名和尝试过的所有模板名
+// 只有通过结构体注册的处理函数才能推断出模板
+func (can *Can) impliedTpl(invoker *Invoker) (string, []string) {
+	if invoker == nil || invoker.kind != invokeByReceiver {
+		return "", nil
+	}
+	resolver := can.tplResolver
+	if resolver == nil {
+		resolver = defaultTplResolver
+	}
+	ctrl := invoker.Type.In(0)
+	if ctrl.Kind() == reflect.Ptr {
+		ctrl = ctrl.Elem()
+	}
+	name := resolver(strings.TrimSuffix(strings.TrimSuffix(ctrl.Name(), "Controller"), "Ctrl"), invoker.Name)
+	var candidates []string
+	for _, suffix := range can.tplSuffix {
+		candidate := name + suffix
+		if can.lookupTpl(candidate) != nil {
+			return candidate, nil
+		}
+		candidates = append(candidates, candidate)
+	}
+	return "", candidates
+}
+
+var modelViewType = reflect.TypeOf(ModelView{})
+
+// checkTpl 开启CheckTpl时，返回值类型为ModelView、且推断出的模板不存在的处理函数返回错误
+// 启动时无法知道处理函数是否会自己设置Tpl，这样的处理函数需要在URI字段上加 tpl:"-" 标签跳过检查
+// 返回interface{}的处理函数无法在启动时判断，不做检查
+func (can *Can) checkTpl(t *routeTable) error {
+	if !can.tplCheck {
+		return nil
+	}
+	if missing := can.missingTpl(t); len(missing) > 0 {
+		return errors.New("cango can't find implied template, set ModelView.Tpl and tag URI with tpl:\"-\" if it's intended:\n\t" + strings.Join(missing, "\n\t"))
+	}
+	return nil
+}
+
+// missingTpl 列出推断出的模板不存在的处理函数
+func (can *Can) missingTpl(t *routeTable) []string {
+	var missing []string
+	seen := map[string]bool{}
+	for _, rr := range t.routes {
+		if rr.invoker == nil || rr.invoker.kind != invokeByReceiver || seen[rr.name] {
+			continue
+		}
+		seen[rr.name] = true
+		typ := rr.invoker.Type
+		if typ.NumOut() == 0 || (typ.Out(0) != modelViewType && typ.Out(0) != reflect.PtrTo(modelViewType)) {
+			continue
+		}
+		if explicitTpl(rr.param) {
+			continue
+		}
+		if name, candidates := can.impliedTpl(rr.invoker); name == "" {
+			missing = append(missing, rr.name+" -> "+strings.Join(candidates, ", "))
+		}
+	}
+	return missing
+}
+
+// explicitTpl 参数的URI字段带有 tpl:"-" 标签时，处理函数自己设置ModelView.Tpl
+func explicitTpl(param reflect.Type) bool {
+	if param == nil || param.Kind() != reflect.Struct {
+		return false
+	}
+	f, ok := param.FieldByName(uriName)
+	return ok && f.Tag.Get(tplTagName) == "-"
+}
@@ -0,0 +1,100 @@
+package cango
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type tplUserCtrl struct {
+	URI `value:"/user"`
+}
+
+func (c *tplUserCtrl) Profile(ps struct {
+	URI `value:"/profile"`
+}) ModelView {
+	return ModelView{Model: "profile"}
+}
+
+func (c *tplUserCtrl) GetOrderList(ps struct {
+	URI `value:"/orders"`
+}) ModelView {
+	return ModelView{Model: "orders"}
+}
+
+func (c *tplUserCtrl) Explicit(ps struct {
+	URI `value:"/explicit" tpl:"-"`
+}) *ModelView {
+	return &ModelView{Tpl: "tpl_user/profile.html", Model: "explicit"}
+}
+
+func (c *tplUserCtrl) Missing(ps struct {
+	URI `value:"/missing"`
+}) ModelView {
+	return ModelView{Model: "missing"}
+}
+
+func newTplCan(t *testing.T) *Can {
+	dir := t.TempDir()
+	_ = os.MkdirAll(filepath.Join(dir, "tpl_user"), 0755)
+	for name, body := range map[string]string{"tpl_user/profile.html": "profile {{.}}", "tpl_user/order_list.tpl": "orders {{.}}"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	can := NewCan().Route(&tplUserCtrl{})
+	can.tplRootPath = dir
+	can.tplSuffix = defaultTplSuffix
+	can.debugTpl = true
+	return can
+}
+
+func TestCan_impliedTpl(t *testing.T) {
+	can := newTplCan(t)
+	can.build()
+	tests := []struct {
+		url      string
+		wantBody string
+	}{
+		{url: "/user/profile", wantBody: "profile profile"},
+		{url: "/user/orders", wantBody: "orders orders"},
+		{url: "/user/explicit", wantBody: "profile explicit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("ServeHTTP() body = %v, want %v", rec.Body.String(), tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestCan_checkTpl(t *testing.T) {
+	can := newTplCan(t)
+	// 没有开启时不检查
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	can.tplCheck = true
+	err := can.Validate()
+	if err == nil || !strings.Contains(err.Error(), "tplUserCtrl.Missing -> tpl_user/missing.tpl, tpl_user/missing.html") {
+		t.Fatalf("Validate() = %v", err)
+	}
+	// 找得到模板的和带有tpl:"-"标签的处理函数不报错
+	for _, name := range []string{"Profile", "GetOrderList", "Explicit"} {
+		if strings.Contains(err.Error(), name) {
+			t.Errorf("Validate() = %v", err)
+		}
+	}
+	can.SetTplResolver(func(ctrl, method string) string {
+		return "tpl_user/profile"
+	})
+	if err := can.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
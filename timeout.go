@@ -0,0 +1,177 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/JessonChan/canlog"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// contextProvider 默认的context.Context参数，客户端断开或者超时（timeout标签）时取消
+// 可以通过Provide替换
+var contextProvider = reflect.ValueOf(func(r *WebRequest) (context.Context, error) {
+	return r.Request.Context(), nil
+})
+
+// parseTimeout 解析URI字段上的timeout标签，如 URI `value:"/report" timeout:"2s"`
+func parseTimeout(tag reflect.StructTag) time.Duration {
+	s := tag.Get(timeoutTagName)
+	if s == "" {
+		return 0
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		canlog.CanError("invalid timeout tag", s, err)
+		return 0
+	}
+	return d
+}
+
+// serveTimeout 在限定的时间内执行处理函数，超时返回503
+// 处理函数在新的goroutine中执行，使用带有deadline的context和受保护的ResponseWriter，超时之后的写入会被丢弃
+// 客户端断开时不再等待处理函数，也不再返回任何内容
+func serveTimeout(invoker *Invoker, match matcher, request *WebRequest) (interface{}, int) {
+	ctx, cancel := context.WithTimeout(request.Request.Context(), invoker.timeout)
+	defer cancel()
+	tw := &timeoutWriter{w: request.ResponseWriter, h: http.Header{}, ctx: ctx}
+	inner := &WebRequest{ResponseWriter: tw, Request: request.Request.WithContext(ctx), invoker: invoker}
+
+	type result struct {
+		handleReturn interface{}
+		code         int
+		panic        interface{}
+	}
+	done := make(chan result, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- result{panic: p}
+			}
+		}()
+		handleReturn, code := invoke(invoker, match, inner)
+		done <- result{handleReturn: handleReturn, code: code}
+	}()
+
+	select {
+	case res := <-done:
+		if res.panic != nil {
+			// 交给ServeHTTP中的recover处理
+			panic(res.panic)
+		}
+		// 处理函数和deadline同时结束时，以deadline为准
+		if ctx.Err() == nil {
+			tw.finish()
+			return res.handleReturn, res.code
+		}
+	case <-ctx.Done():
+	}
+	wrote := tw.timeout()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		canlog.CanDebug(request.Request.Method, request.Request.URL.Path, ctx.Err())
+		return nil, http.StatusOK
+	}
+	canlog.CanError(request.Request.Method, request.Request.URL.Path, "handler timeout", invoker.timeout)
+	if wrote {
+		return nil, http.StatusOK
+	}
+	return errorReturn{err: NewProblem(http.StatusServiceUnavailable, "handler timeout")}, http.StatusOK
+}
+
+// timeoutWriter 超时或者客户端断开之后丢弃处理函数的写入，header在写入时才复制到原来的ResponseWriter
+type timeoutWriter struct {
+	w           http.ResponseWriter
+	ctx         context.Context
+	h           http.Header
+	mu          sync.Mutex
+	timedOut    bool
+	wroteHeader bool
+}
+
+func (tw *timeoutWriter) Header() http.Header {
+	return tw.h
+}
+
+func (tw *timeoutWriter) Write(p []byte) (int, error) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.ctx.Err() != nil {
+		return 0, http.ErrHandlerTimeout
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	return tw.w.Write(p)
+}
+
+func (tw *timeoutWriter) WriteHeader(code int) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.ctx.Err() != nil {
+		return
+	}
+	tw.writeHeaderLocked(code)
+}
+
+func (tw *timeoutWriter) writeHeaderLocked(code int) {
+	if tw.wroteHeader {
+		return
+	}
+	tw.wroteHeader = true
+	tw.copyHeader()
+	tw.w.WriteHeader(code)
+}
+
+func (tw *timeoutWriter) copyHeader() {
+	dst := tw.w.Header()
+	for k, vv := range tw.h {
+		dst[k] = vv
+	}
+}
+
+// Flush 支持流式返回
+func (tw *timeoutWriter) Flush() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if tw.timedOut || tw.ctx.Err() != nil {
+		return
+	}
+	tw.writeHeaderLocked(http.StatusOK)
+	if f, ok := tw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// finish 处理函数正常返回，把还没有写入的header交给原来的ResponseWriter
+func (tw *timeoutWriter) finish() {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if !tw.wroteHeader {
+		tw.copyHeader()
+	}
+}
+
+// timeout 标记超时，返回处理函数是否已经写入了响应
+func (tw *timeoutWriter) timeout() bool {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.timedOut = true
+	return tw.wroteHeader
+}
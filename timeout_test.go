@@ -0,0 +1,102 @@
+package cango
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type timeoutCtrl struct {
+	URI `value:"/timeout"`
+	// done 记录处理函数结束时context的错误
+	done chan error
+}
+
+func (c *timeoutCtrl) Fast(ps struct {
+	URI `value:"/fast" timeout:"1s"`
+}, ctx context.Context) interface{} {
+	ps.Request().ResponseWriter.Header().Set("X-Fast", "1")
+	if _, ok := ctx.Deadline(); !ok {
+		return Content{String: "no deadline"}
+	}
+	return Content{String: "fast"}
+}
+
+func (c *timeoutCtrl) Slow(ps struct {
+	URI `value:"/slow" timeout:"20ms"`
+}, ctx context.Context) interface{} {
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+	}
+	c.done <- ctx.Err()
+	_, _ = ps.Request().ResponseWriter.Write([]byte("late"))
+	return Content{String: "slow"}
+}
+
+func (c *timeoutCtrl) Wait(ps struct {
+	URI `value:"/wait"`
+}, ctx context.Context) interface{} {
+	<-ctx.Done()
+	c.done <- ctx.Err()
+	return nil
+}
+
+func (c *timeoutCtrl) Panic(ps struct {
+	URI `value:"/panic" timeout:"1s"`
+}) interface{} {
+	panic("boom")
+}
+
+func TestCan_ServeHTTP_timeout(t *testing.T) {
+	ctrl := &timeoutCtrl{done: make(chan error, 1)}
+	can := NewCan().Route(ctrl)
+	if err := can.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/timeout/fast", nil))
+	if rec.Code != http.StatusOK || rec.Body.String() != "fast" || rec.Header().Get("X-Fast") != "1" {
+		t.Errorf("fast = %v %v %v", rec.Code, rec.Body.String(), rec.Header())
+	}
+
+	rec = httptest.NewRecorder()
+	can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/timeout/slow", nil))
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Content-Type") != mimeProblemJSON {
+		t.Errorf("slow = %v %v", rec.Code, rec.Body.String())
+	}
+	if err := <-ctrl.done; err != context.DeadlineExceeded {
+		t.Errorf("slow ctx.Err() = %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/timeout/panic", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("panic = %v %v", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCan_ServeHTTP_clientDisconnect(t *testing.T) {
+	ctrl := &timeoutCtrl{done: make(chan error, 1)}
+	can := NewCan().Route(ctrl)
+	can.build()
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan struct{})
+	go func() {
+		can.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/timeout/wait", nil).WithContext(ctx))
+		close(served)
+	}()
+	cancel()
+	select {
+	case err := <-ctrl.done:
+		if err != context.Canceled {
+			t.Errorf("wait ctx.Err() = %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler is not canceled")
+	}
+	<-served
+}
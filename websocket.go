@@ -0,0 +1,413 @@
+// Copyright 2020 Cango Author.
+
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+
+//    http://www.apache.org/licenses/LICENSE-2.0
+
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cango
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JessonChan/canlog"
+)
+
+// websocketGUID RFC 6455 中用于计算Sec-WebSocket-Accept的GUID
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// defaultMaxMessageSize WebSocket默认的单条消息大小限制
+const defaultMaxMessageSize = 1 << 20
+
+// websocketCloseTimeout 发送close帧之后等待对方回复的时间
+const websocketCloseTimeout = time.Second
+
+// MessageType WebSocket的消息类型
+type MessageType int
+
+const (
+	TextMessage   MessageType = 1
+	BinaryMessage MessageType = 2
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// WebSocket 关闭码，见 RFC 6455 7.4.1
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseUnsupportedData  = 1003
+	CloseNoStatusReceived = 1005
+	CloseInvalidPayload   = 1007
+	CloseMessageTooBig    = 1009
+	CloseInternalError    = 1011
+)
+
+// WebSocket 做为处理函数的返回值时，把GET请求升级为WebSocket连接
+// 握手请求和普通请求一样经过filter和参数绑定，可以在filter中做鉴权
+//
+//	func (c *ChatCtrl) Room(ps struct {
+//		cango.URI `value:"/room/{id}"`
+//		Id int
+//	}) interface{} {
+//		return cango.WebSocket{Handler: func(conn *cango.WebSocketConn) error {...}}
+//	}
+type WebSocket struct {
+	// Handler 在升级之后执行，返回时关闭连接，返回error时使用1011关闭
+	Handler func(conn *WebSocketConn) error
+	// MaxMessageSize 单条消息的大小限制，为0时为1MB，超过时使用1009关闭
+	MaxMessageSize int64
+	// Subprotocols 支持的子协议，按照客户端请求的顺序选择第一个支持的
+	Subprotocols []string
+}
+
+// CloseError 对方关闭了连接或者因为协议错误关闭了连接
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed: %d %s", e.Code, e.Text)
+}
+
+// WebSocketConn 升级之后的WebSocket连接，可以在一个goroutine中读，同时在另外的goroutine中写
+// Handler返回之后连接会被关闭，Handler中启动的读goroutine会在关闭时收到错误
+type WebSocketConn struct {
+	// Request 握手时的请求
+	Request *http.Request
+	// Subprotocol 协商出的子协议，没有时为空
+	Subprotocol string
+
+	conn    net.Conn
+	br      *bufio.Reader
+	maxSize int64
+
+	wmu       sync.Mutex
+	closeSent bool
+	// rmu 保证同一时间只有一个goroutine在读，Close时用它等待正在读的goroutine返回
+	rmu sync.Mutex
+	// closeRecv 已经收到了对方的close帧，持有rmu时使用
+	closeRecv bool
+}
+
+// ReadMessage 读取一条完整的消息，自动回复ping，忽略pong
+// 对方关闭或者协议错误时返回 *CloseError
+func (c *WebSocketConn) ReadMessage() (MessageType, []byte, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	if c.closeRecv {
+		return 0, nil, &CloseError{Code: CloseNoStatusReceived}
+	}
+	var msgOp byte
+	var msg []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			// 控制帧可以夹在分片的消息中间
+			continue
+		case opPong:
+			continue
+		case opClose:
+			ce := &CloseError{Code: CloseNoStatusReceived}
+			if len(payload) == 1 {
+				return 0, nil, c.fail(CloseProtocolError, "invalid close frame")
+			}
+			if len(payload) >= 2 {
+				ce.Code = int(binary.BigEndian.Uint16(payload))
+				ce.Text = string(payload[2:])
+				// 1005/1006/1015等关闭码不能出现在close帧中，不能原样回复
+				if !validCloseCode(ce.Code) {
+					return 0, nil, c.fail(CloseProtocolError, "invalid close code")
+				}
+				if !utf8.ValidString(ce.Text) {
+					return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8")
+				}
+			}
+			c.closeRecv = true
+			_ = c.writeClose(ce.Code, "")
+			return 0, nil, ce
+		case opText, opBinary:
+			if msgOp != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expect continuation frame")
+			}
+			msgOp, msg = op, payload
+		case opContinuation:
+			if msgOp == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+			if int64(len(msg)+len(payload)) > c.maxSize {
+				return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+			}
+			msg = append(msg, payload...)
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+		if msgOp != 0 && fin {
+			if msgOp == opText && !utf8.Valid(msg) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8")
+			}
+			return MessageType(msgOp), msg, nil
+		}
+	}
+}
+
+// WriteMessage 写入一条消息，服务端发出的帧不使用掩码
+func (c *WebSocketConn) WriteMessage(t MessageType, data []byte) error {
+	if t != TextMessage && t != BinaryMessage {
+		return errors.New("websocket: unknown message type")
+	}
+	return c.writeFrame(byte(t), data)
+}
+
+// Ping 发送ping，对方的pong在ReadMessage中被忽略
+func (c *WebSocketConn) Ping(data []byte) error {
+	return c.writeFrame(opPing, data)
+}
+
+// Close 发送close帧，等待对方回复之后关闭连接，已经收到对方的close帧时直接关闭
+// 有其它goroutine正在读时，等它读到对方的close帧或者超时返回之后再读，不会同时读连接
+func (c *WebSocketConn) Close(code int, reason string) error {
+	err := c.writeClose(code, reason)
+	_ = c.conn.SetReadDeadline(time.Now().Add(websocketCloseTimeout))
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+	for err == nil && !c.closeRecv {
+		var op byte
+		if _, op, _, err = c.readFrame(); op == opClose {
+			break
+		}
+	}
+	return c.conn.Close()
+}
+
+// validCloseCode 判断关闭码是否可以在close帧中发送，见 RFC 6455 7.4
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 3000 && code <= 4999:
+		return true
+	case code >= CloseNormalClosure && code <= 1014:
+		return code != 1004 && code != CloseNoStatusReceived && code != 1006
+	}
+	return false
+}
+
+// fail 因为协议错误关闭连接
+func (c *WebSocketConn) fail(code int, text string) error {
+	_ = c.writeClose(code, text)
+	_ = c.conn.Close()
+	return &CloseError{Code: code, Text: text}
+}
+
+func (c *WebSocketConn) writeClose(code int, reason string) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return nil
+	}
+	c.closeSent = true
+	// 控制帧的payload不能超过125字节
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	var payload []byte
+	if code != CloseNoStatusReceived {
+		payload = make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		payload = append(payload, reason...)
+	}
+	return c.writeFrameLocked(opClose, payload)
+}
+
+func (c *WebSocketConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0f
+	if head[0]&0x70 != 0 {
+		return fin, op, nil, c.fail(CloseProtocolError, "reserved bits are set")
+	}
+	// 客户端发出的帧必须使用掩码
+	if head[1]&0x80 == 0 {
+		return fin, op, nil, c.fail(CloseProtocolError, "frame is not masked")
+	}
+	size := int64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		size = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		size = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+	if op >= opClose && (!fin || size > 125) {
+		return fin, op, nil, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if size < 0 || size > c.maxSize {
+		return fin, op, nil, c.fail(CloseMessageTooBig, "message too big")
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, size)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+func (c *WebSocketConn) writeFrame(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	if c.closeSent {
+		return errors.New("websocket: close sent")
+	}
+	return c.writeFrameLocked(op, payload)
+}
+
+// writeFrameLocked 写入一个完整的帧，调用时需要持有wmu
+func (c *WebSocketConn) writeFrameLocked(op byte, payload []byte) error {
+	frame := make([]byte, 0, 10+len(payload))
+	frame = append(frame, 0x80|op)
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(n))
+		frame = append(append(frame, 127), ext[:]...)
+	}
+	frame = append(frame, payload...)
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// serveWebSocket 校验握手请求，升级连接并执行Handler
+func serveWebSocket(w http.ResponseWriter, r *http.Request, ws WebSocket) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	switch {
+	case r.Method != http.MethodGet,
+		!headerContainsToken(r.Header, "Connection", "upgrade"),
+		!headerContainsToken(r.Header, "Upgrade", "websocket"),
+		len(key) == 0:
+		writeProblem(w, r, NewProblem(http.StatusBadRequest, "not a websocket handshake"))
+		return
+	case r.Header.Get("Sec-WebSocket-Version") != "13":
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeProblem(w, r, NewProblem(http.StatusUpgradeRequired, "unsupported websocket version"))
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		canlog.CanError(r.Method, r.URL.Path, "websocket: ResponseWriter is not a http.Hijacker")
+		writeProblem(w, r, NewProblem(http.StatusInternalServerError, ""))
+		return
+	}
+	var subprotocol string
+	for _, p := range headerTokens(r.Header, "Sec-WebSocket-Protocol") {
+		for _, sp := range ws.Subprotocols {
+			if subprotocol == "" && p == sp {
+				subprotocol = sp
+			}
+		}
+	}
+	netConn, brw, err := hj.Hijack()
+	if err != nil {
+		canlog.CanError(r.Method, r.URL.Path, err)
+		return
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	handshake := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n"
+	if subprotocol != "" {
+		handshake += "Sec-WebSocket-Protocol: " + subprotocol + "\r\n"
+	}
+	if _, err := netConn.Write([]byte(handshake + "\r\n")); err != nil {
+		_ = netConn.Close()
+		return
+	}
+	// 握手时设置的超时不再适用于长连接
+	_ = netConn.SetDeadline(time.Time{})
+	conn := &WebSocketConn{Request: r, Subprotocol: subprotocol, conn: netConn, br: brw.Reader, maxSize: ws.MaxMessageSize}
+	if conn.maxSize <= 0 {
+		conn.maxSize = defaultMaxMessageSize
+	}
+	code := CloseNormalClosure
+	if ws.Handler != nil {
+		if err := ws.Handler(conn); err != nil {
+			var ce *CloseError
+			if !errors.As(err, &ce) {
+				canlog.CanError(r.Method, r.URL.Path, err)
+				code = CloseInternalError
+			}
+		}
+	}
+	_ = conn.Close(code, "")
+}
+
+// headerTokens 以逗号分隔的header值
+func headerTokens(h http.Header, name string) (tokens []string) {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tokens = append(tokens, t)
+			}
+		}
+	}
+	return tokens
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, t := range headerTokens(h, name) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
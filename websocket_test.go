@@ -0,0 +1,216 @@
+package cango
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type wsAuthFilter struct {
+	Filter `value:"/ws/*"`
+}
+
+func (f *wsAuthFilter) PreHandle(request *WebRequest) interface{} {
+	if request.Request.URL.Query().Get("token") != "secret" {
+		return ContentWithCode{Code: http.StatusUnauthorized, String: "unauthorized"}
+	}
+	return true
+}
+
+func (f *wsAuthFilter) PostHandle(request *WebRequest) interface{} {
+	return true
+}
+
+type wsCtrl struct {
+	URI `value:"/ws"`
+}
+
+func (c *wsCtrl) Echo(ps struct {
+	URI    `value:"/echo/{room}"`
+	Room   string
+	Prefix string
+}) interface{} {
+	return WebSocket{MaxMessageSize: 16, Subprotocols: []string{"chat"}, Handler: func(conn *WebSocketConn) error {
+		for {
+			t, msg, err := conn.ReadMessage()
+			if err != nil {
+				return err
+			}
+			if err := conn.WriteMessage(t, []byte(ps.Room+":"+ps.Prefix+string(msg))); err != nil {
+				return err
+			}
+		}
+	}}
+}
+
+// Spawn 在Handler中启动读goroutine，不等它结束就返回
+func (c *wsCtrl) Spawn(ps struct {
+	URI `value:"/spawn"`
+}) interface{} {
+	return WebSocket{Handler: func(conn *WebSocketConn) error {
+		go func() {
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+		return conn.WriteMessage(TextMessage, []byte("bye"))
+	}}
+}
+
+// wsClient 最简单的WebSocket客户端，只用于测试
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, addr, path string) (*wsClient, *http.Response) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := make([]byte, 16)
+	_, _ = rand.Read(key)
+	_, _ = io.WriteString(conn, "GET "+path+" HTTP/1.1\r\nHost: "+addr+"\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: "+base64.StdEncoding.EncodeToString(key)+"\r\nSec-WebSocket-Version: 13\r\nSec-WebSocket-Protocol: json, chat\r\n\r\n")
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(2 * time.Second))
+	return &wsClient{t: t, conn: conn, br: br}, resp
+}
+
+func (c *wsClient) send(fin bool, op byte, payload []byte) {
+	head := []byte{op, 0x80}
+	if fin {
+		head[0] |= 0x80
+	}
+	if len(payload) < 126 {
+		head[1] |= byte(len(payload))
+	} else {
+		head[1] |= 126
+		head = append(head, byte(len(payload)>>8), byte(len(payload)))
+	}
+	mask := []byte{1, 2, 3, 4}
+	masked := make([]byte, len(payload))
+	for i := range payload {
+		masked[i] = payload[i] ^ mask[i%4]
+	}
+	if _, err := c.conn.Write(append(append(head, mask...), masked...)); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *wsClient) read() (byte, []byte) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		c.t.Fatal(err)
+	}
+	if head[1]&0x80 != 0 {
+		c.t.Error("server frame is masked")
+	}
+	size := int(head[1] & 0x7f)
+	if size == 126 {
+		var ext [2]byte
+		_, _ = io.ReadFull(c.br, ext[:])
+		size = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		c.t.Fatal(err)
+	}
+	return head[0] & 0x0f, payload
+}
+
+func (c *wsClient) expectClose(code int) {
+	op, payload := c.read()
+	if op != opClose || len(payload) < 2 || int(binary.BigEndian.Uint16(payload)) != code {
+		c.t.Errorf("read() = %v %q, want close %v", op, payload, code)
+	}
+}
+
+func TestCan_ServeHTTP_webSocket(t *testing.T) {
+	can := NewCan().Route(&wsCtrl{}).Filter(&wsAuthFilter{})
+	can.build()
+	srv := httptest.NewServer(can)
+	defer srv.Close()
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	c, resp := dialWebSocket(t, addr, "/ws/echo/lobby?token=secret&prefix=>")
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Protocol") != "chat" {
+		t.Fatalf("handshake = %v %v", resp.StatusCode, resp.Header)
+	}
+
+	c.send(true, opText, []byte("hi"))
+	if op, msg := c.read(); op != opText || string(msg) != "lobby:>hi" {
+		t.Errorf("echo = %v %q", op, msg)
+	}
+	// 分片的消息，中间夹着ping
+	c.send(false, opBinary, []byte("ab"))
+	c.send(true, opPing, []byte("p"))
+	if op, msg := c.read(); op != opPong || string(msg) != "p" {
+		t.Errorf("pong = %v %q", op, msg)
+	}
+	c.send(true, opContinuation, []byte("cd"))
+	if op, msg := c.read(); op != opBinary || string(msg) != "lobby:>abcd" {
+		t.Errorf("fragmented echo = %v %q", op, msg)
+	}
+	c.send(true, opClose, []byte{0x03, 0xe8})
+	c.expectClose(CloseNormalClosure)
+	_ = c.conn.Close()
+
+	c, _ = dialWebSocket(t, addr, "/ws/echo/lobby?token=secret")
+	c.send(true, opText, []byte(strings.Repeat("x", 17)))
+	c.expectClose(CloseMessageTooBig)
+	_ = c.conn.Close()
+
+	c, _ = dialWebSocket(t, addr, "/ws/echo/lobby?token=secret")
+	c.send(true, opText, []byte{0xff, 0xfe})
+	c.expectClose(CloseInvalidPayload)
+	_ = c.conn.Close()
+
+	// 不能原样回复的关闭码
+	for _, code := range []uint16{CloseNoStatusReceived, 1006, 1015, 999, 5000} {
+		c, _ = dialWebSocket(t, addr, "/ws/echo/lobby?token=secret")
+		c.send(true, opClose, []byte{byte(code >> 8), byte(code)})
+		c.expectClose(CloseProtocolError)
+		_ = c.conn.Close()
+	}
+
+	// Handler留下的读goroutine和Close不会同时读连接
+	c, _ = dialWebSocket(t, addr, "/ws/spawn?token=secret")
+	c.send(true, opText, []byte("hi"))
+	if op, msg := c.read(); op != opText || string(msg) != "bye" {
+		t.Errorf("spawn = %v %q", op, msg)
+	}
+	c.expectClose(CloseNormalClosure)
+	c.send(true, opClose, []byte{0x03, 0xe8})
+	if _, err := c.br.ReadByte(); err != io.EOF {
+		t.Errorf("after close = %v, want EOF", err)
+	}
+	_ = c.conn.Close()
+
+	c, resp = dialWebSocket(t, addr, "/ws/echo/lobby")
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("unauthorized handshake = %v", resp.StatusCode)
+	}
+	_ = c.conn.Close()
+
+	rec := httptest.NewRecorder()
+	can.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/ws/echo/lobby?token=secret", nil))
+	if rec.Code != http.StatusBadRequest || rec.Header().Get("Content-Type") != mimeProblemJSON {
+		t.Errorf("plain request = %v %v", rec.Code, rec.Body.String())
+	}
+}